@@ -0,0 +1,20 @@
+// Package v1alpha1 contains the API types of the secret-replicator.fussionlabs.com group
+// +kubebuilder:object:generate=true
+// +groupName=secret-replicator.fussionlabs.com
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion Group version used to register these objects
+	GroupVersion = schema.GroupVersion{Group: "secret-replicator.fussionlabs.com", Version: "v1alpha1"}
+
+	// SchemeBuilder Adds the types of this group version to a scheme
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme Adds the types of this group version to a scheme
+	AddToScheme = SchemeBuilder.AddToScheme
+)
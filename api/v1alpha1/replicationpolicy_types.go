@@ -0,0 +1,57 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReplicationPolicySpec Source secrets selected by a policy and the namespaces they are replicated to
+type ReplicationPolicySpec struct {
+	// Selector Labels of the source secrets replicated by the policy
+	Selector metav1.LabelSelector `json:"selector"`
+	// SourceNamespaces Namespaces the source secrets are selected in, all namespaces if empty
+	// +optional
+	SourceNamespaces []string `json:"sourceNamespaces,omitempty"`
+	// TargetNamespaces Namespaces or glob patterns the selected secrets are replicated to, like allowed-namespaces
+	// +optional
+	TargetNamespaces []string `json:"targetNamespaces,omitempty"`
+	// ExcludedNamespaces Namespaces the selected secrets are never replicated to, like excluded-namespaces
+	// +optional
+	ExcludedNamespaces []string `json:"excludedNamespaces,omitempty"`
+	// NamespaceSelector Labels of the namespaces the selected secrets are replicated to, like namespace-selector
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// ReplicationPolicyStatus Source secrets a policy currently applies to
+type ReplicationPolicyStatus struct {
+	// Sources Source secrets replicated by the policy, as <namespace>/<name>
+	// +optional
+	Sources []string `json:"sources,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+
+// ReplicationPolicy Replicates the secrets matching a selector without annotating each of them. Secrets with their own
+// replication-allowed annotation keep their configuration.
+type ReplicationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReplicationPolicySpec   `json:"spec,omitempty"`
+	Status ReplicationPolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReplicationPolicyList List of ReplicationPolicy
+type ReplicationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReplicationPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ReplicationPolicy{}, &ReplicationPolicyList{})
+}
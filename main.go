@@ -17,24 +17,36 @@ limitations under the License.
 package main
 
 import (
+	"com.dm0275/secret-replicator-controller/api/v1alpha1"
 	"com.dm0275/secret-replicator-controller/pkg/controller"
+	replicawebhook "com.dm0275/secret-replicator-controller/pkg/webhook"
+	"com.dm0275/secret-replicator-controller/utils"
 	"crypto/tls"
 	"flag"
+	"fmt"
 	"go.uber.org/zap/zapcore"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -45,6 +57,7 @@ var (
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
 
 	//+kubebuilder:scaffold:scheme
 }
@@ -65,6 +78,7 @@ func main() {
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
 	flag.Parse()
+	enableLeaderElection = enableLeaderElection || utils.GetEnvBool("LEADER_ELECT", false)
 
 	// Setup zap logging options
 	zapOpts := zap.Options{
@@ -95,12 +109,34 @@ func main() {
 		TLSOpts: tlsOpts,
 	})
 
+	// Managed and failing source secrets, also listed on the replications and failures endpoints of the metrics server
+	secretList := &controller.SourceList{}
+	secretFailures := &controller.FailureList{}
+	secretResync := &controller.ResyncTrigger{}
+
+	// Time in-flight reconciles get to finish on shutdown
+	gracefulShutdownTimeout, err := utils.GetEnvDuration("GRACEFUL_SHUTDOWN_TIMEOUT", controller.DefaultGracefulShutdownTimeout)
+	if err != nil {
+		setupLog.Error(err, "invalid GRACEFUL_SHUTDOWN_TIMEOUT, using the default graceful shutdown timeout", "timeout", controller.DefaultGracefulShutdownTimeout)
+	}
+
+	// The inspection endpoints expose the target namespaces and errors of the sources and resyncs can be forced
+	// through the metrics server, only serve them when it is served securely
+	metricsHandlers := map[string]http.Handler{}
+	if secureMetrics {
+		metricsHandlers[controller.ReplicationsPath] = controller.NewReplicationsHandler(secretList)
+		metricsHandlers[controller.FailuresPath] = controller.NewFailuresHandler(secretFailures)
+		metricsHandlers[controller.ResyncPath] = controller.NewResyncHandler(secretResync)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme: scheme,
+		Scheme:                  scheme,
+		GracefulShutdownTimeout: &gracefulShutdownTimeout,
 		Metrics: metricsserver.Options{
 			BindAddress:   metricsAddr,
 			SecureServing: secureMetrics,
 			TLSOpts:       tlsOpts,
+			ExtraHandlers: metricsHandlers,
 		},
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
@@ -112,35 +148,239 @@ func main() {
 		// speeds up voluntary leader transitions as the new leader don't have to wait
 		// LeaseDuration time first.
 		//
-		// In the default scaffold provided, the program ends immediately after
-		// the manager stops, so would be fine to enable this option. However,
-		// if you are doing or is intended to do any operation such as perform cleanups
-		// after the manager stops then its usage might be unsafe.
-		// LeaderElectionReleaseOnCancel: true,
+		// The program ends immediately after the manager stops, so the leader can step down.
+		LeaderElectionReleaseOnCancel: true,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	if err = (&controller.SecretReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
+	defaultReconcileInterval, err := utils.GetEnvDuration("DEFAULT_RECONCILE_INTERVAL", controller.DefaultReconcileInterval)
+	if err != nil {
+		setupLog.Error(err, "invalid DEFAULT_RECONCILE_INTERVAL, using the default reconcile interval", "interval", controller.DefaultReconcileInterval)
+	}
+
+	minReconcileInterval, err := utils.GetEnvDuration("MIN_RECONCILE_INTERVAL", controller.DefaultMinReconcileInterval)
+	if err != nil {
+		setupLog.Error(err, "invalid MIN_RECONCILE_INTERVAL, using the default minimum reconcile interval", "interval", controller.DefaultMinReconcileInterval)
+	}
+
+	reconcileJitter, err := utils.GetEnvFloat("RECONCILE_JITTER", controller.DefaultReconcileJitter)
+	if err != nil || reconcileJitter < 0 || reconcileJitter >= 1 {
+		setupLog.Error(err, "invalid RECONCILE_JITTER, using the default reconcile jitter", "jitter", controller.DefaultReconcileJitter)
+		reconcileJitter = controller.DefaultReconcileJitter
+	}
+
+	replicationConcurrency, err := utils.GetEnvInt("REPLICATION_CONCURRENCY", 1)
+	if err != nil {
+		setupLog.Error(err, "invalid REPLICATION_CONCURRENCY, replicating to one namespace at a time")
+	}
+
+	namespacePageSize, err := utils.GetEnvInt("NAMESPACE_PAGE_SIZE", 0)
+	if err != nil {
+		setupLog.Error(err, "invalid NAMESPACE_PAGE_SIZE, listing all namespaces at once")
+	}
+
+	maxTargetNamespaces, err := utils.GetEnvInt("MAX_TARGET_NAMESPACES", 0)
+	if err != nil {
+		setupLog.Error(err, "invalid MAX_TARGET_NAMESPACES, not limiting the number of target namespaces")
+	}
+
+	coalesceWindow, err := utils.GetEnvDuration("COALESCE_WINDOW", controller.DefaultCoalesceWindow)
+	if err != nil {
+		setupLog.Error(err, "invalid COALESCE_WINDOW, using the default coalesce window", "window", controller.DefaultCoalesceWindow)
+	}
+
+	newNamespaceDelay, err := utils.GetEnvDuration("NEW_NAMESPACE_DELAY", 0)
+	if err != nil {
+		setupLog.Error(err, "invalid NEW_NAMESPACE_DELAY, replicating to new namespaces immediately")
+	}
+
+	// The kill switch is disabled unless a <namespace>/<name> is configured
+	killSwitch := types.NamespacedName{}
+	if configMap := utils.GetEnv("KILL_SWITCH_CONFIGMAP", ""); configMap != "" {
+		namespace, name, found := strings.Cut(configMap, "/")
+		if !found || namespace == "" || name == "" {
+			setupLog.Error(fmt.Errorf("kill switch configmap %s isn't <namespace>/<name>", configMap), "invalid KILL_SWITCH_CONFIGMAP, disabling the kill switch")
+		} else {
+			killSwitch = types.NamespacedName{Namespace: namespace, Name: name}
+		}
+	}
+
+	tlsExpiryWarning, err := utils.GetEnvDuration("TLS_EXPIRY_WARNING", 0)
+	if err != nil {
+		setupLog.Error(err, "invalid TLS_EXPIRY_WARNING, not warning about expiring certificates")
+	}
+
+	// An empty list denies no secret types
+	deniedSecretTypes := []string{}
+	if secretTypes := utils.GetEnv("DENIED_SECRET_TYPES", ""); secretTypes != "" {
+		deniedSecretTypes = strings.Split(secretTypes, ",")
+	}
+
+	foreignManagerAnnotations := []string{}
+	if annotations := utils.GetEnv("FOREIGN_MANAGER_ANNOTATIONS", ""); annotations != "" {
+		foreignManagerAnnotations = strings.Split(annotations, ",")
+	}
+
+	syncMode := utils.GetEnv("SYNC_MODE", controller.SyncModePeriodic)
+	if syncMode != controller.SyncModePeriodic && syncMode != controller.SyncModeWatchOnly {
+		setupLog.Error(fmt.Errorf("unknown sync mode %s", syncMode), "invalid SYNC_MODE, using the periodic sync mode")
+		syncMode = controller.SyncModePeriodic
+	}
+
+	globalAllowedNamespaces := []string{}
+	if namespaces := utils.GetEnv("GLOBAL_ALLOWED_NAMESPACES", ""); namespaces != "" {
+		globalAllowedNamespaces = strings.Split(namespaces, ",")
+	}
+
+	replicationOptions := controller.ReplicationOptions{
+		AnnotationPrefix:             utils.GetEnv("ANNOTATION_PREFIX", controller.DefaultAnnotationPrefix),
+		ProtectedNamespaces:          strings.Split(utils.GetEnv("PROTECTED_NAMESPACES", controller.DefaultProtectedNamespaces), ","),
+		StrictNamespaceValidation:    utils.GetEnvBool("STRICT_NAMESPACE_VALIDATION", false),
+		RequireExplicitAllNamespaces: utils.GetEnvBool("REQUIRE_EXPLICIT_ALL_NAMESPACES", false),
+		DefaultReconcileInterval:     defaultReconcileInterval,
+		MinReconcileInterval:         minReconcileInterval,
+		ReconcileJitter:              reconcileJitter,
+		ReplicationConcurrency:       replicationConcurrency,
+		GlobalAllowedNamespaces:      globalAllowedNamespaces,
+		NamespacePageSize:            int64(namespacePageSize),
+		SyncMode:                     syncMode,
+		MaxTargetNamespaces:          maxTargetNamespaces,
+		TLSExpiryWarning:             tlsExpiryWarning,
+		CoalesceWindow:               coalesceWindow,
+		NewNamespaceDelay:            newNamespaceDelay,
+		KillSwitch:                   killSwitch,
+		DeniedSecretTypes:            deniedSecretTypes,
+		ForeignManagerAnnotations:    foreignManagerAnnotations,
+	}
+
+	clientTimeout, err := utils.GetEnvDuration("CLIENT_TIMEOUT", controller.DefaultClientTimeout)
+	if err != nil {
+		setupLog.Error(err, "invalid CLIENT_TIMEOUT, using the default client timeout", "timeout", controller.DefaultClientTimeout)
+	}
+	managerClient := controller.NewTimeoutClient(mgr.GetClient(), clientTimeout)
+	apiReader := controller.NewTimeoutReader(mgr.GetAPIReader(), clientTimeout)
+
+	// Load the client of the remote cluster secrets can be replicated to
+	targetClients := map[string]client.Client{}
+	if targetKubeconfig := utils.GetEnv("TARGET_KUBECONFIG", ""); targetKubeconfig != "" {
+		targetConfig, err := clientcmd.BuildConfigFromFlags("", targetKubeconfig)
+		if err != nil {
+			setupLog.Error(err, "unable to load target kubeconfig", "path", targetKubeconfig)
+			os.Exit(1)
+		}
+
+		targetClient, err := client.New(targetConfig, client.Options{Scheme: mgr.GetScheme()})
+		if err != nil {
+			setupLog.Error(err, "unable to create target cluster client", "path", targetKubeconfig)
+			os.Exit(1)
+		}
+		targetClients[utils.GetEnv("TARGET_CLUSTER_NAME", "remote")] = controller.NewTimeoutClient(targetClient, clientTimeout)
+	}
+
+	secretReconciler := &controller.SecretReconciler{
+		Client:             managerClient,
+		APIReader:          apiReader,
+		ReplicationOptions: replicationOptions,
+		Scheme:             mgr.GetScheme(),
+		DryRun:             utils.GetEnvBool("DRY_RUN", false),
+		TargetClients:      targetClients,
+		SecretList:         secretList,
+		Failures:           secretFailures,
+		Resync:             secretResync,
+	}
+	if err = secretReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Secret")
 		os.Exit(1)
 	}
 
+	if err = (&controller.ConfigMapReconciler{
+		Client:             managerClient,
+		APIReader:          apiReader,
+		ReplicationOptions: replicationOptions,
+		Scheme:             mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ConfigMap")
+		os.Exit(1)
+	}
+
+	// ReplicationPolicies require the CRD, installed by the helm chart
+	if utils.GetEnvBool("ENABLE_REPLICATION_POLICIES", false) {
+		if err = (&controller.ReplicationPolicyReconciler{
+			Client:             managerClient,
+			ReplicationOptions: replicationOptions,
+			Scheme:             mgr.GetScheme(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ReplicationPolicy")
+			os.Exit(1)
+		}
+	}
+
+	if utils.GetEnvBool("ENABLE_REPLICA_WEBHOOK", false) {
+		// Without the username the webhook would deny the controller's own updates of replicas
+		controllerUsername := utils.GetEnv("CONTROLLER_SERVICE_ACCOUNT", "")
+		if controllerUsername == "" {
+			setupLog.Error(fmt.Errorf("CONTROLLER_SERVICE_ACCOUNT is not set"), "unable to set up the replica webhook")
+			os.Exit(1)
+		}
+
+		mgr.GetWebhookServer().Register(replicawebhook.ReplicaValidatorPath, &webhook.Admission{
+			Handler: &replicawebhook.ReplicaValidator{
+				ReplicationOptions: replicationOptions,
+				ControllerUsername: controllerUsername,
+				Decoder:            admission.NewDecoder(mgr.GetScheme()),
+			},
+		})
+	}
+
+	if utils.GetEnvBool("ENABLE_SOURCE_WEBHOOK", false) {
+		mgr.GetWebhookServer().Register(replicawebhook.SourceValidatorPath, &webhook.Admission{
+			Handler: &replicawebhook.SourceValidator{
+				ReplicationOptions: replicationOptions,
+				Decoder:            admission.NewDecoder(mgr.GetScheme()),
+			},
+		})
+	}
+
+	if utils.GetEnvBool("ENABLE_INTERVAL_WEBHOOK", false) {
+		mgr.GetWebhookServer().Register(replicawebhook.IntervalDefaulterPath, &webhook.Admission{
+			Handler: &replicawebhook.IntervalDefaulter{
+				ReplicationOptions: replicationOptions,
+				Decoder:            admission.NewDecoder(mgr.GetScheme()),
+			},
+		})
+	}
+
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
 	}
+	if err := mgr.AddHealthzCheck("reconcile-progress", secretReconciler.LivenessCheck); err != nil {
+		setupLog.Error(err, "unable to set up reconcile progress check")
+		os.Exit(1)
+	}
 	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if err := mgr.AddReadyzCheck("initial-sync", secretReconciler.InitialSyncCheck); err != nil {
+		setupLog.Error(err, "unable to set up initial sync check")
+		os.Exit(1)
+	}
+
+	// Re-reconcile every managed secret on SIGHUP, e.g. after changing the global configuration
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for range hangup {
+			setupLog.Info("received SIGHUP, resyncing all secrets")
+			secretResync.Trigger()
+		}
+	}()
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"context"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"time"
+)
+
+// DefaultCoalesceWindow Delay of the fan-out enqueues, repeated enqueues of a source within it run a single reconcile
+var DefaultCoalesceWindow = time.Duration(1 * time.Second)
+
+// coalescingHandler Enqueues the requests of a map func after the CoalesceWindow. The workqueue keeps a single entry per
+// source while it waits, so a burst of events, e.g. namespaces created in quick succession, results in one reconcile of
+// each source instead of one per event.
+type coalescingHandler struct {
+	window     time.Duration
+	toRequests handler.MapFunc
+}
+
+// coalescingHandler Build the handler of a fan-out watch, a window of 0 enqueues immediately
+func (o ReplicationOptions) coalescingHandler(toRequests handler.MapFunc) handler.EventHandler {
+	return &coalescingHandler{window: o.CoalesceWindow, toRequests: toRequests}
+}
+
+func (h *coalescingHandler) Create(ctx context.Context, e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(ctx, e.Object, q)
+}
+
+func (h *coalescingHandler) Update(ctx context.Context, e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(ctx, e.ObjectOld, q)
+	h.enqueue(ctx, e.ObjectNew, q)
+}
+
+func (h *coalescingHandler) Delete(ctx context.Context, e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(ctx, e.Object, q)
+}
+
+func (h *coalescingHandler) Generic(ctx context.Context, e event.GenericEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(ctx, e.Object, q)
+}
+
+func (h *coalescingHandler) enqueue(ctx context.Context, obj client.Object, q workqueue.RateLimitingInterface) {
+	if obj == nil {
+		return
+	}
+
+	for _, request := range h.toRequests(ctx, obj) {
+		if h.window <= 0 {
+			q.Add(request)
+		} else {
+			q.AddAfter(request, h.window)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package controller
+
+import (
+	"com.dm0275/secret-replicator-controller/utils"
+	"context"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
+	"reflect"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type ConfigMapReconciler struct {
+	client.Client
+	ReplicationOptions
+	Scheme        *runtime.Scheme
+	Recorder      record.EventRecorder
+	ConfigMapList SourceList
+	// APIReader Reads from the API server without the cache, used to list namespaces in pages
+	APIReader client.Reader
+}
+
+func (r *ConfigMapReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("secret-replicator")
+	}
+	if r.APIReader == nil {
+		r.APIReader = mgr.GetAPIReader()
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.ConfigMap{}, builder.WithPredicates(r.sourceChangedPredicate(configMapContent))).
+		Watches(&v1.Namespace{},
+			r.coalescingHandler(r.namespaceToSources),
+			builder.WithPredicates(namespaceChangedPredicate)).
+		Watches(&v1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.killSwitchToSources)).
+		Complete(r)
+}
+
+// configMapContent Fields of a configmap that are copied to its replicas
+func configMapContent(obj client.Object) interface{} {
+	configMap, ok := obj.(*v1.ConfigMap)
+	if !ok {
+		return nil
+	}
+
+	return struct {
+		Data       map[string]string
+		BinaryData map[string][]byte
+	}{configMap.Data, configMap.BinaryData}
+}
+
+// namespaceToSources Enqueue every managed source when a namespace is created or relabeled, target filtering happens in Reconcile
+func (r *ConfigMapReconciler) namespaceToSources(ctx context.Context, obj client.Object) []reconcile.Request {
+	return requestsFor(r.ConfigMapList.Items())
+}
+
+func (r *ConfigMapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if r.replicationKilled(ctx, r.Client) {
+		logger.Info(fmt.Sprintf("replication is disabled by the kill switch configmap %s, skipping configmap %s", r.KillSwitch, req.NamespacedName))
+		return ctrl.Result{}, nil
+	}
+
+	var configMap v1.ConfigMap
+	if err := r.Get(ctx, req.NamespacedName, &configMap); err != nil {
+		// Check if the configmap is deleted
+		if errors.IsNotFound(err) {
+			r.ConfigMapList.Remove(req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	finalizer := r.annotation(finalizerKey)
+
+	// Clean up replicas if the source configmap is being deleted
+	if !configMap.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(&configMap, finalizer) {
+			r.ConfigMapList.Remove(req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, r.finalizeConfigMap(ctx, &configMap, finalizer)
+	}
+
+	// Validate configmap configuration, invalid configmaps aren't managed until their configuration is fixed
+	err := r.validateConfiguration(ctx, r.Client, r.Recorder, &configMap)
+	if err == nil {
+		err = r.validateConfigMapAnnotations(&configMap)
+	}
+	if err != nil {
+		logger.Error(err, "invalid configmap annotation configuration")
+		r.ConfigMapList.Remove(req.NamespacedName)
+		return ctrl.Result{}, err
+	}
+
+	if r.replicateEnabled(&configMap) {
+		// If replication is enabled, add the configmap to the ConfigMapList
+		r.ConfigMapList.Add(req.NamespacedName)
+	} else {
+		r.ConfigMapList.Remove(req.NamespacedName)
+
+		// Release the source configmap if replication was disabled after the finalizer was added
+		if controllerutil.RemoveFinalizer(&configMap, finalizer) {
+			if err := r.Client.Update(ctx, &configMap); err != nil {
+				logger.Error(err, fmt.Sprintf("error removing finalizer from configmap %s", configMap.Name))
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Add the finalizer so replicas can be cleaned up when the source configmap is deleted
+	if controllerutil.AddFinalizer(&configMap, finalizer) {
+		if err := r.Client.Update(ctx, &configMap); err != nil {
+			logger.Error(err, fmt.Sprintf("error adding finalizer to configmap %s", configMap.Name))
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Leave existing replicas untouched while replication is paused
+	if r.replicationPaused(&configMap) {
+		logger.Info(fmt.Sprintf("replication of configmap %s is paused", configMap.Name))
+		return ctrl.Result{}, nil
+	}
+
+	reconciliationInterval := r.getReconciliationInterval(ctx, &configMap)
+	targetNamespaces, err := r.getTargetNamespaces(ctx, r.namespaceReader(r.Client, r.APIReader), r.Recorder, &configMap, nil)
+	if err != nil {
+		logger.Error(err, "error listing namespaces")
+		return ctrl.Result{RequeueAfter: reconciliationInterval}, err
+	}
+	targetNamespaces = r.skipGloballyDisallowedNamespaces(ctx, r.Recorder, &configMap, targetNamespaces)
+	if r.tooManyTargets(ctx, r.Recorder, &configMap, targetNamespaces) {
+		return ctrl.Result{RequeueAfter: reconciliationInterval}, nil
+	}
+
+	replicationErrs := []error{}
+	for _, namespace := range targetNamespaces {
+		if err := r.createConfigMap(ctx, configMap, namespace); err != nil {
+			replicationErrs = append(replicationErrs, fmt.Errorf("namespace %s: %w", namespace, err))
+		}
+	}
+
+	// Remove replicas from namespaces that are no longer in scope
+	err = r.deleteOrphanedReplicas(ctx, &configMap, targetNamespaces)
+	if err != nil {
+		logger.Error(err, "error cleaning up orphaned replicas")
+		replicationErrs = append(replicationErrs, err)
+	}
+
+	// Return replication failures so the configmap is retried with backoff instead of waiting for the interval
+	if len(replicationErrs) > 0 {
+		return ctrl.Result{}, utilerrors.NewAggregate(replicationErrs)
+	}
+
+	return ctrl.Result{RequeueAfter: reconciliationInterval}, nil
+}
+
+// secretOnlyKeys Annotations only supported on secrets, configmaps setting them are rejected instead of being
+// replicated without them
+var secretOnlyKeys = []string{allowedNamespacesFromKey, targetNameKey, includeKeysKey, excludeKeysKey}
+
+// validateConfigMapAnnotations Reject configmaps that set annotations only supported on secrets
+func (r *ConfigMapReconciler) validateConfigMapAnnotations(configMap *v1.ConfigMap) error {
+	for _, key := range secretOnlyKeys {
+		if _, ok := configMap.Annotations[r.annotation(key)]; ok {
+			return fmt.Errorf("unable to replicate %s, %s is only supported on secrets", configMap.Name, r.annotation(key))
+		}
+	}
+	return nil
+}
+
+// finalizeConfigMap Delete all replicas of a source configmap and remove its finalizer
+func (r *ConfigMapReconciler) finalizeConfigMap(ctx context.Context, configMap *v1.ConfigMap, finalizer string) error {
+	logger := log.FromContext(ctx)
+
+	replicas, err := r.listReplicas(ctx, configMap)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("error listing replicas of configmap %s", configMap.Name))
+		return err
+	}
+
+	// Delete the replicas unless the source opted out of cleanup
+	if r.cleanupOnDelete(configMap) {
+		for _, replica := range replicas {
+			deleteErr := r.Client.Delete(ctx, &replica)
+			if deleteErr != nil && !errors.IsNotFound(deleteErr) {
+				logger.Error(deleteErr, fmt.Sprintf("error deleting configmap %s in namespace %s", replica.Name, replica.Namespace))
+				return deleteErr
+			}
+
+			logger.Info(fmt.Sprintf("deleted configmap %s in namespace %s", replica.Name, replica.Namespace))
+		}
+	}
+
+	controllerutil.RemoveFinalizer(configMap, finalizer)
+	if err := r.Client.Update(ctx, configMap); err != nil {
+		logger.Error(err, fmt.Sprintf("error removing finalizer from configmap %s", configMap.Name))
+		return err
+	}
+
+	r.ConfigMapList.Remove(client.ObjectKeyFromObject(configMap))
+	return nil
+}
+
+// deleteOrphanedReplicas Delete replicas of the source configmap that live outside the target namespaces
+func (r *ConfigMapReconciler) deleteOrphanedReplicas(ctx context.Context, sourceConfigMap *v1.ConfigMap, targetNamespaces []string) error {
+	logger := log.FromContext(ctx)
+
+	replicas, err := r.listReplicas(ctx, sourceConfigMap)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("error listing replicas of configmap %s", sourceConfigMap.Name))
+		return err
+	}
+
+	deleteErrs := []error{}
+	for _, replica := range replicas {
+		if utils.ListContains(targetNamespaces, replica.Namespace) {
+			continue
+		}
+
+		deleteErr := r.Client.Delete(ctx, &replica)
+		if deleteErr != nil && !errors.IsNotFound(deleteErr) {
+			logger.Error(deleteErr, fmt.Sprintf("error deleting orphaned configmap %s in namespace %s", replica.Name, replica.Namespace))
+			deleteErrs = append(deleteErrs, deleteErr)
+			continue
+		}
+
+		logger.Info(fmt.Sprintf("deleted orphaned configmap %s in namespace %s", replica.Name, replica.Namespace))
+	}
+
+	return utilerrors.NewAggregate(deleteErrs)
+}
+
+// listReplicas List all configmaps carrying a replicated-from annotation that points at the source configmap
+func (r *ConfigMapReconciler) listReplicas(ctx context.Context, sourceConfigMap *v1.ConfigMap) ([]v1.ConfigMap, error) {
+	var configMaps v1.ConfigMapList
+	err := r.Client.List(ctx, &configMaps)
+	if err != nil {
+		return nil, err
+	}
+
+	replicas := []v1.ConfigMap{}
+	for _, configMap := range configMaps.Items {
+		if r.isReplicaOf(&configMap, sourceConfigMap) {
+			replicas = append(replicas, configMap)
+		}
+	}
+
+	return replicas, nil
+}
+
+func (r *ConfigMapReconciler) createConfigMap(ctx context.Context, sourceConfigMap v1.ConfigMap, ns string) error {
+	logger := log.FromContext(ctx)
+
+	// Never replicate a configmap into its own namespace
+	if r.isSourceNamespace(&sourceConfigMap, ns) {
+		logger.Info(fmt.Sprintf("configmap %s in the %s namespace is a source configmap", sourceConfigMap.Name, ns))
+		return nil
+	}
+
+	// Copy the source so the replica never shares its data maps with the cached source configmap
+	sourceConfigMap = *sourceConfigMap.DeepCopy()
+
+	var configMap v1.ConfigMap
+	getErr := r.Client.Get(ctx, client.ObjectKey{Name: sourceConfigMap.Name, Namespace: ns}, &configMap)
+	if getErr != nil && errors.IsNotFound(getErr) {
+		newConfigMap := &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        sourceConfigMap.Name,
+				Namespace:   ns,
+				Labels:      r.replicaLabels(&sourceConfigMap),
+				Annotations: r.replicaAnnotations(&sourceConfigMap),
+			},
+			Data:       sourceConfigMap.Data,
+			BinaryData: sourceConfigMap.BinaryData,
+		}
+
+		createErr := r.Client.Create(ctx, newConfigMap)
+		if errors.IsAlreadyExists(createErr) {
+			// The configmap was created since the cache was read, the requeued reconcile updates it once the cache
+			// caught up
+			logger.Info(fmt.Sprintf("configmap %s was created in namespace %s concurrently, retrying", newConfigMap.Name, ns))
+			return createErr
+		} else if createErr != nil {
+			logger.Error(createErr, fmt.Sprintf("error replicating configmap %s to namespace %s", newConfigMap.Name, newConfigMap.Namespace))
+			r.Recorder.Eventf(&sourceConfigMap, v1.EventTypeWarning, eventReasonReplicationFailed, "error replicating to namespace %s: %v", ns, createErr)
+			return createErr
+		}
+
+		logger.Info(fmt.Sprintf("replicated configmap %s to namespace %s", newConfigMap.Name, newConfigMap.Namespace))
+		r.Recorder.Eventf(&sourceConfigMap, v1.EventTypeNormal, eventReasonReplicated, "replicated to namespace %s", ns)
+	} else if getErr == nil {
+		// Don't fight over configmaps managed by another tool
+		if key, ok := r.foreignManagerAnnotation(&configMap); ok {
+			logger.Info(fmt.Sprintf("not replicating configmap %s to namespace %s, configmap %s is managed by another tool with annotation %s", sourceConfigMap.Name, ns, configMap.Name, key))
+			r.Recorder.Eventf(&sourceConfigMap, v1.EventTypeWarning, eventReasonConflictSkipped, "configmap %s in namespace %s is managed by another tool with annotation %s", configMap.Name, ns, key)
+			return nil
+		}
+
+		// Refuse to overwrite a replica that belongs to another source
+		replicatedFrom, ok := r.getReplicatedFrom(&configMap)
+		if ok && replicatedFrom != replicaSource(&sourceConfigMap) {
+			logger.Info(fmt.Sprintf("not replicating configmap %s to namespace %s, configmap %s is already replicated from %s", sourceConfigMap.Name, ns, configMap.Name, replicatedFrom))
+			r.Recorder.Eventf(&sourceConfigMap, v1.EventTypeWarning, eventReasonConflictSkipped, "configmap %s in namespace %s is already replicated from %s", configMap.Name, ns, replicatedFrom)
+			return nil
+		}
+
+		// Refuse to overwrite a configmap that wasn't created by the controller unless the source opted in
+		if !ok && !r.overwriteExisting(&sourceConfigMap) {
+			logger.Info(fmt.Sprintf("not replicating configmap %s to namespace %s, configmap %s already exists and isn't a replica", sourceConfigMap.Name, ns, configMap.Name))
+			r.Recorder.Eventf(&sourceConfigMap, v1.EventTypeWarning, eventReasonConflictSkipped, "configmap %s in namespace %s already exists and isn't a replica", configMap.Name, ns)
+			return nil
+		}
+
+		// Check if the configmap is up to date
+		if reflect.DeepEqual(sourceConfigMap.Data, configMap.Data) && reflect.DeepEqual(sourceConfigMap.BinaryData, configMap.BinaryData) && !r.replicaMetadataChanged(&configMap, &sourceConfigMap) {
+			logger.Info(fmt.Sprintf("configmap %s is already up-to-date in namespace %s", configMap.Name, ns))
+			return nil
+		}
+
+		// Replace the data instead of merging it, so keys removed from the source are removed from the replica
+		configMap.Data = sourceConfigMap.Data
+		r.mergeReplicaMetadata(&configMap, &sourceConfigMap)
+		configMap.BinaryData = sourceConfigMap.BinaryData
+
+		updateErr := r.Client.Update(ctx, &configMap)
+		if updateErr != nil {
+			logger.Error(updateErr, fmt.Sprintf("error updating configmap %s in namespace %s", configMap.Name, configMap.Namespace))
+			r.Recorder.Eventf(&sourceConfigMap, v1.EventTypeWarning, eventReasonReplicationFailed, "error updating replica in namespace %s: %v", ns, updateErr)
+			return updateErr
+		}
+
+		logger.Info(fmt.Sprintf("updated configmap %s in namespace %s", configMap.Name, configMap.Namespace))
+		r.Recorder.Eventf(&sourceConfigMap, v1.EventTypeNormal, eventReasonUpdated, "updated replica in namespace %s", ns)
+		return nil
+	} else {
+		logger.Error(getErr, fmt.Sprintf("error checking if configmap %s exists in namespace %s", sourceConfigMap.Name, ns))
+		return getErr
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sync"
+)
+
+// failure Last reconcile error of a source and the last error of each target namespace it failed to replicate to
+type failure struct {
+	Source     string            `json:"source"`
+	Error      string            `json:"error"`
+	Time       metav1.Time       `json:"time"`
+	Namespaces map[string]string `json:"namespaces,omitempty"`
+}
+
+// FailureList Concurrency-safe record of the sources whose last reconcile failed
+type FailureList struct {
+	mu       sync.RWMutex
+	failures map[types.NamespacedName]failure
+	// namespaces Last error of the target namespaces of each source, kept across reconciles failing for other reasons
+	namespaces map[types.NamespacedName]map[string]string
+}
+
+// Record Store the result of a reconcile, a nil error clears the failure of the source and its target namespaces
+func (l *FailureList) Record(item types.NamespacedName, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err == nil {
+		delete(l.failures, item)
+		if _, ok := l.namespaces[item]; ok {
+			delete(l.namespaces, item)
+			forgetNamespaceFailures(item)
+		}
+		return
+	}
+
+	if l.failures == nil {
+		l.failures = map[types.NamespacedName]failure{}
+	}
+	l.failures[item] = failure{Source: item.String(), Error: err.Error(), Time: metav1.Now()}
+}
+
+// RecordNamespace Store the result of replicating a source to a target namespace, a nil error clears the failure of
+// the namespace
+func (l *FailureList) RecordNamespace(item types.NamespacedName, namespace string, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err == nil {
+		if _, ok := l.namespaces[item][namespace]; ok {
+			delete(l.namespaces[item], namespace)
+			namespaceFailures.DeleteLabelValues(item.Namespace, item.Name, namespace)
+		}
+		return
+	}
+
+	if l.namespaces == nil {
+		l.namespaces = map[types.NamespacedName]map[string]string{}
+	}
+	if l.namespaces[item] == nil {
+		l.namespaces[item] = map[string]string{}
+	}
+	l.namespaces[item][namespace] = err.Error()
+	namespaceFailures.WithLabelValues(item.Namespace, item.Name, namespace).Set(1)
+}
+
+// Items Return a copy of the failures
+func (l *FailureList) Items() []failure {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	items := make([]failure, 0, len(l.failures))
+	for source, item := range l.failures {
+		if len(l.namespaces[source]) > 0 {
+			item.Namespaces = make(map[string]string, len(l.namespaces[source]))
+			for namespace, err := range l.namespaces[source] {
+				item.Namespaces[namespace] = err
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strconv"
+)
+
+// killSwitchEnabledKey Key of the kill switch ConfigMap, replication stops while it is false
+var killSwitchEnabledKey = "enabled"
+
+// replicationKilled Check if the KillSwitch ConfigMap disables replication. A missing ConfigMap, key or unparsable
+// value keeps replication enabled.
+func (o ReplicationOptions) replicationKilled(ctx context.Context, c client.Reader) bool {
+	if o.KillSwitch.Name == "" {
+		return false
+	}
+
+	var configMap v1.ConfigMap
+	if err := c.Get(ctx, o.KillSwitch, &configMap); err != nil {
+		if !errors.IsNotFound(err) {
+			log.FromContext(ctx).Error(err, fmt.Sprintf("error reading kill switch configmap %s", o.KillSwitch))
+		}
+		return false
+	}
+
+	enabled, err := strconv.ParseBool(configMap.Data[killSwitchEnabledKey])
+	if err != nil {
+		return false
+	}
+	return !enabled
+}
+
+// isKillSwitch Check if an object is the KillSwitch ConfigMap
+func (o ReplicationOptions) isKillSwitch(obj client.Object) bool {
+	return o.KillSwitch.Name != "" && client.ObjectKeyFromObject(obj) == o.KillSwitch
+}
+
+// killSwitchToSources Enqueue every source secret with replication enabled when the kill switch changes, including the
+// sources created or deleted while replication was disabled
+func (r *SecretReconciler) killSwitchToSources(ctx context.Context) []reconcile.Request {
+	var secrets v1.SecretList
+	if err := r.List(ctx, &secrets); err != nil {
+		log.FromContext(ctx).Error(err, "error listing secrets after a kill switch change")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for i := range secrets.Items {
+		if r.replicateEnabled(&secrets.Items[i]) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&secrets.Items[i])})
+		}
+	}
+	return requests
+}
+
+// killSwitchToSources Enqueue every source configmap with replication enabled when the kill switch changes
+func (r *ConfigMapReconciler) killSwitchToSources(ctx context.Context, obj client.Object) []reconcile.Request {
+	if !r.isKillSwitch(obj) {
+		return nil
+	}
+
+	var configMaps v1.ConfigMapList
+	if err := r.List(ctx, &configMaps); err != nil {
+		log.FromContext(ctx).Error(err, "error listing configmaps after a kill switch change")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for i := range configMaps.Items {
+		if r.replicateEnabled(&configMaps.Items[i]) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&configMaps.Items[i])})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"com.dm0275/secret-replicator-controller/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"time"
+)
+
+// sourceObservation Revision of a source secret and the time it was first observed at, written to the observed
+// annotation so the replication latency survives restarts and the controller's own writes to the source
+type sourceObservation struct {
+	Revision string           `json:"revision"`
+	Time     metav1.MicroTime `json:"time"`
+}
+
+// observedRevision Hash of the fields of a source secret its replicas are built from, the annotations written by
+// the controller are left out
+func (o ReplicationOptions) observedRevision(secret *v1.Secret) (string, error) {
+	return utils.HashObject(struct {
+		Type        v1.SecretType
+		Data        map[string][]byte
+		Labels      map[string]string
+		Annotations map[string]string
+	}{secret.Type, sourceSecretData(secret), secret.Labels, o.sourceAnnotations(secret)})
+}
+
+// getObservation Read the observed annotation of a source secret, false if it is missing or invalid
+func (o ReplicationOptions) getObservation(secret *v1.Secret) (sourceObservation, bool) {
+	var observation sourceObservation
+	value, ok := secret.Annotations[o.annotation(observedKey)]
+	if !ok || json.Unmarshal([]byte(value), &observation) != nil {
+		return sourceObservation{}, false
+	}
+	return observation, true
+}
+
+// observeSource Record the time the current revision of a source secret was first observed at in its observed
+// annotation, the annotation is kept while the revision doesn't change
+func (r *SecretReconciler) observeSource(ctx context.Context, c client.Client, secret *v1.Secret) error {
+	logger := log.FromContext(ctx)
+
+	revision, err := r.observedRevision(secret)
+	if err != nil {
+		return err
+	}
+
+	if observation, ok := r.getObservation(secret); ok && observation.Revision == revision {
+		return nil
+	}
+
+	observationJSON, err := json.Marshal(sourceObservation{Revision: revision, Time: metav1.NewMicroTime(r.now())})
+	if err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(secret.DeepCopy())
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[r.annotation(observedKey)] = string(observationJSON)
+
+	if err := c.Patch(ctx, secret, patch); err != nil {
+		logger.Error(err, fmt.Sprintf("error recording the observation of secret %s", secret.Name))
+		return err
+	}
+
+	return nil
+}
+
+// observedAt Get the time the current revision of a source secret was first observed at, false if it wasn't recorded
+func (r *SecretReconciler) observedAt(secret *v1.Secret) (time.Time, bool) {
+	observation, ok := r.getObservation(secret)
+	if !ok {
+		return time.Time{}, false
+	}
+	return observation.Time.Time, true
+}
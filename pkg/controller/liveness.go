@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"k8s.io/apimachinery/pkg/types"
+	"sync"
+	"time"
+)
+
+// resyncIntervals Base reconcile interval of each source that is requeued periodically, the liveness check waits a
+// multiple of the largest one
+type resyncIntervals struct {
+	mu    sync.Mutex
+	items map[types.NamespacedName]time.Duration
+}
+
+// set Record the base reconcile interval of a source
+func (i *resyncIntervals) set(key types.NamespacedName, interval time.Duration) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.items == nil {
+		i.items = map[types.NamespacedName]time.Duration{}
+	}
+	i.items[key] = interval
+}
+
+// remove Forget the reconcile interval of a source that is no longer requeued periodically
+func (i *resyncIntervals) remove(key types.NamespacedName) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.items, key)
+}
+
+// max Get the largest reconcile interval of all sources, 0 if no source is requeued periodically
+func (i *resyncIntervals) max() time.Duration {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var largest time.Duration
+	for _, interval := range i.items {
+		if interval > largest {
+			largest = interval
+		}
+	}
+	return largest
+}
@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sort"
+	"strings"
+)
+
+// mergeSources List the enabled source secrets merged into a merge target, sorted by namespace and name
+func (r *SecretReconciler) mergeSources(ctx context.Context, target types.NamespacedName) ([]v1.Secret, error) {
+	var secrets v1.SecretList
+	if err := r.Client.List(ctx, &secrets); err != nil {
+		return nil, err
+	}
+
+	sources := []v1.Secret{}
+	for _, secret := range secrets.Items {
+		if !secret.DeletionTimestamp.IsZero() || !r.replicateEnabled(&secret) {
+			continue
+		}
+
+		if mergeTarget, ok, err := r.getMergeTarget(&secret); err == nil && ok && mergeTarget == target {
+			sources = append(sources, secret)
+		}
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		return replicaSource(&sources[i]) < replicaSource(&sources[j])
+	})
+	return sources, nil
+}
+
+// mergeData Merge the replicated data of the sources of a merge target, a key set to different values by
+// two sources is a conflict
+func (r *SecretReconciler) mergeData(sources []v1.Secret, target types.NamespacedName) (map[string][]byte, error) {
+	var data map[string][]byte
+	keySources := map[string]string{}
+	for i := range sources {
+		sourceData, err := r.replicaData(&sources[i], target.Namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		for key, value := range sourceData {
+			if keySource, ok := keySources[key]; ok && !bytes.Equal(data[key], value) {
+				return nil, fmt.Errorf("key %s of secret %s conflicts with secret %s in merge target %s", key, replicaSource(&sources[i]), keySource, target)
+			}
+
+			if data == nil {
+				data = map[string][]byte{}
+			}
+			data[key] = value
+			keySources[key] = replicaSource(&sources[i])
+		}
+	}
+
+	return data, nil
+}
+
+// mergeTargetAllowed Check the namespace of a merge target like a target namespace, merge targets in protected, not
+// globally allowed, missing, opted out or terminating namespaces are refused
+func (r *SecretReconciler) mergeTargetAllowed(ctx context.Context, sourceSecret *v1.Secret, target types.NamespacedName) (bool, error) {
+	namespaces := []string{target.Namespace}
+	if !r.protectedNamespacesAllowed(sourceSecret) {
+		namespaces = r.skipProtectedNamespaces(ctx, r.Recorder, sourceSecret, namespaces)
+	}
+	namespaces = r.skipGloballyDisallowedNamespaces(ctx, r.Recorder, sourceSecret, namespaces)
+
+	namespaces, err := r.skipIneligibleNamespaces(ctx, r.Client, r.Recorder, sourceSecret, namespaces)
+	if err != nil {
+		return false, err
+	}
+	return len(namespaces) > 0, nil
+}
+
+// reconcileMergeTarget Write the merged data of all sources sharing the merge target of a source secret
+func (r *SecretReconciler) reconcileMergeTarget(ctx context.Context, sourceSecret *v1.Secret, target types.NamespacedName) error {
+	logger := log.FromContext(ctx)
+
+	sources, err := r.mergeSources(ctx, target)
+	if err != nil {
+		errorsTotal.WithLabelValues(operationList).Inc()
+		return err
+	}
+
+	var secret v1.Secret
+	getErr := r.Client.Get(ctx, target, &secret)
+	if getErr != nil && !errors.IsNotFound(getErr) {
+		logger.Error(getErr, fmt.Sprintf("error checking if secret %s exists in namespace %s", target.Name, target.Namespace))
+		return getErr
+	}
+	exists := getErr == nil
+
+	// Refuse to overwrite a secret that isn't a merge target unless the source opted in
+	_, merged := secret.Annotations[r.annotation(mergedFromKey)]
+	if exists && !merged && !r.overwriteExisting(sourceSecret) {
+		logger.Info(fmt.Sprintf("not merging secret %s into %s, secret %s already exists and isn't a merge target", sourceSecret.Name, target, target.Name))
+		r.Recorder.Eventf(sourceSecret, v1.EventTypeWarning, eventReasonConflictSkipped, "secret %s in namespace %s already exists and isn't a merge target", target.Name, target.Namespace)
+		return nil
+	}
+
+	// Remove the merge target once its last source is gone
+	if len(sources) == 0 {
+		if !exists || r.DryRun {
+			return nil
+		}
+
+		if err := r.Client.Delete(ctx, &secret); err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, fmt.Sprintf("error deleting merged secret %s in namespace %s", target.Name, target.Namespace))
+			errorsTotal.WithLabelValues(operationDelete).Inc()
+			return err
+		}
+
+		logger.Info(fmt.Sprintf("deleted merged secret %s in namespace %s", target.Name, target.Namespace))
+		return nil
+	}
+
+	data, err := r.mergeData(sources, target)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("error merging secrets into %s", target))
+		for i := range sources {
+			r.Recorder.Eventf(&sources[i], v1.EventTypeWarning, eventReasonMergeConflict, "%v", err)
+		}
+		return err
+	}
+
+	dataHash, err := replicaDataHash(v1.SecretTypeOpaque, data)
+	if err != nil {
+		return err
+	}
+
+	mergedFrom := []string{}
+	for i := range sources {
+		mergedFrom = append(mergedFrom, replicaSource(&sources[i]))
+	}
+
+	if exists && secret.Annotations[r.annotation(mergedFromKey)] == strings.Join(mergedFrom, ",") &&
+		secret.Annotations[r.annotation(dataHashKey)] == dataHash && !r.replicaDrifted(&secret) {
+		logger.Info(fmt.Sprintf("merged secret %s is already up-to-date in namespace %s", target.Name, target.Namespace))
+		return nil
+	}
+
+	if !exists {
+		secret = v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: target.Name, Namespace: target.Namespace}}
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[r.annotation(mergedFromKey)] = strings.Join(mergedFrom, ",")
+	secret.Annotations[r.annotation(dataHashKey)] = dataHash
+	secret.Type = v1.SecretTypeOpaque
+	secret.Data = data
+
+	if r.DryRun {
+		r.logDryRun(ctx, &secret, sourceSecret, "merge %s into secret %s in namespace %s", strings.Join(mergedFrom, ","), target.Name, target.Namespace)
+		return nil
+	}
+
+	if exists {
+		err = r.Client.Update(ctx, &secret)
+	} else {
+		err = r.Client.Create(ctx, &secret)
+	}
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("error writing merged secret %s in namespace %s", target.Name, target.Namespace))
+		r.Recorder.Eventf(sourceSecret, v1.EventTypeWarning, eventReasonReplicationFailed, "error writing merged secret %s in namespace %s: %v", target.Name, target.Namespace, err)
+		errorsTotal.WithLabelValues(operationUpdate).Inc()
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("merged %s into secret %s in namespace %s", strings.Join(mergedFrom, ","), target.Name, target.Namespace))
+	r.Recorder.Eventf(sourceSecret, v1.EventTypeNormal, eventReasonReplicated, "merged into secret %s in namespace %s", target.Name, target.Namespace)
+	replicatedTotal.WithLabelValues(sourceSecret.Namespace).Inc()
+	return nil
+}
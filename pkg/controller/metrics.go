@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"time"
+)
+
+var (
+	operationCreate = "create"
+	operationUpdate = "update"
+	operationDelete = "delete"
+	operationList   = "list"
+)
+
+var (
+	outcomeSuccess = "success"
+	outcomeError   = "error"
+)
+
+var (
+	replicatedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secret_replicator_replicated_total",
+			Help: "Number of secrets replicated, labeled by source namespace",
+		},
+		[]string{"source_namespace"},
+	)
+	errorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secret_replicator_errors_total",
+			Help: "Number of replication errors, labeled by operation",
+		},
+		[]string{"operation"},
+	)
+	managedSecrets = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "secret_replicator_managed_secrets",
+			Help: "Number of source secrets managed by the controller",
+		},
+	)
+	reconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "secret_replicator_reconcile_duration_seconds",
+			Help:    "Duration of source secret reconciles, labeled by outcome",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"outcome"},
+	)
+	replicationLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "secret_replicator_replication_latency_seconds",
+			Help:    "Time from a source secret change being observed to a replica being written, labeled by source namespace",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"source_namespace"},
+	)
+	tlsExpiry = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secret_replicator_tls_expiry_seconds",
+			Help: "Expiry of the certificate of TLS source secrets as a Unix timestamp, labeled by source namespace and name",
+		},
+		[]string{"source_namespace", "source_name"},
+	)
+	replicaPlacement = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secret_replicator_replicas",
+			Help: "Replicas of source secrets, 1 for each target namespace a source is replicated to, labeled by source namespace and name and target namespace",
+		},
+		[]string{"source_namespace", "source_name", "target_namespace"},
+	)
+	namespaceFailures = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secret_replicator_namespace_failures",
+			Help: "Target namespaces source secrets failed to replicate to, 1 until the namespace is replicated to again, labeled by source namespace and name and target namespace",
+		},
+		[]string{"source_namespace", "source_name", "target_namespace"},
+	)
+)
+
+// observeReplicaPlacement Replace the replica placement of a source with the namespaces it is replicated to
+func observeReplicaPlacement(source types.NamespacedName, namespaces []string) {
+	forgetReplicaPlacement(source)
+	for _, namespace := range namespaces {
+		replicaPlacement.WithLabelValues(source.Namespace, source.Name, namespace).Set(1)
+	}
+}
+
+// forgetReplicaPlacement Remove the replica placement of a source that is no longer managed
+func forgetReplicaPlacement(source types.NamespacedName) {
+	replicaPlacement.DeletePartialMatch(prometheus.Labels{"source_namespace": source.Namespace, "source_name": source.Name})
+}
+
+// forgetNamespaceFailures Remove the namespace failures of a source
+func forgetNamespaceFailures(source types.NamespacedName) {
+	namespaceFailures.DeletePartialMatch(prometheus.Labels{"source_namespace": source.Namespace, "source_name": source.Name})
+}
+
+// observeReconcileDuration Record the duration of a reconcile that started at start
+func observeReconcileDuration(start time.Time, err error) {
+	outcome := outcomeSuccess
+	if err != nil {
+		outcome = outcomeError
+	}
+	reconcileDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+}
+
+func init() {
+	metrics.Registry.MustRegister(replicatedTotal, errorsTotal, managedSecrets, reconcileDuration, replicationLatency, tlsExpiry, replicaPlacement, namespaceFailures)
+}
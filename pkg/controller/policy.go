@@ -0,0 +1,265 @@
+package controller
+
+import (
+	"com.dm0275/secret-replicator-controller/api/v1alpha1"
+	"com.dm0275/secret-replicator-controller/utils"
+	"context"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"reflect"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sort"
+	"strings"
+)
+
+// sourcePolicyField Index of the secrets by the policy annotation, so the sources of a policy are listed without
+// listing every secret
+var sourcePolicyField = "policy"
+
+// policyAnnotationKeys Source annotations written from a policy, removed once the policy no longer applies
+var policyAnnotationKeys = []string{
+	replicationAllowedKey,
+	allowedNamespacesKey,
+	excludedNamespacesKey,
+	namespaceSelectorKey,
+	policyKey,
+}
+
+// ReplicationPolicyReconciler Converts ReplicationPolicies to the annotations of the secrets they select, the
+// SecretReconciler replicates them like any annotated source
+type ReplicationPolicyReconciler struct {
+	client.Client
+	ReplicationOptions
+	Scheme *runtime.Scheme
+}
+
+func (r *ReplicationPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	policyAnnotation := r.annotation(policyKey)
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1.Secret{}, sourcePolicyField, func(obj client.Object) []string {
+		if policy, ok := obj.GetAnnotations()[policyAnnotation]; ok {
+			return []string{policy}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ReplicationPolicy{}).
+		Watches(&v1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.secretToPolicies)).
+		Complete(r)
+}
+
+// secretToPolicies Enqueue the policies selecting a secret and the policy it was annotated by
+func (r *ReplicationPolicyReconciler) secretToPolicies(ctx context.Context, obj client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+
+	var policies v1alpha1.ReplicationPolicyList
+	if err := r.List(ctx, &policies); err != nil {
+		logger.Error(err, "error listing replication policies")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for i := range policies.Items {
+		policy := &policies.Items[i]
+		if obj.GetAnnotations()[r.annotation(policyKey)] == policy.Name || r.policySelects(policy, obj) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: policy.Name}})
+		}
+	}
+	return requests
+}
+
+// policySelects Check if a policy selects a secret, replicas are never selected even though they copy the source labels
+func (r *ReplicationPolicyReconciler) policySelects(policy *v1alpha1.ReplicationPolicy, obj client.Object) bool {
+	if _, ok := r.getReplicatedFrom(obj); ok {
+		return false
+	}
+
+	if len(policy.Spec.SourceNamespaces) > 0 && !utils.ListContains(policy.Spec.SourceNamespaces, obj.GetNamespace()) {
+		return false
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.Selector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+func (r *ReplicationPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var policy v1alpha1.ReplicationPolicy
+	if err := r.Get(ctx, req.NamespacedName, &policy); err != nil {
+		// The sources of a deleted policy are no longer replicated
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, r.releaseSources(ctx, req.Name, map[types.NamespacedName]bool{})
+		}
+		return ctrl.Result{}, err
+	}
+
+	annotations, err := r.policyAnnotations(&policy)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("invalid replication policy %s", policy.Name))
+		return ctrl.Result{}, err
+	}
+
+	var secrets v1.SecretList
+	if err := r.List(ctx, &secrets); err != nil {
+		logger.Error(err, "error listing secrets")
+		return ctrl.Result{}, err
+	}
+
+	policyErrs := []error{}
+	applied := map[types.NamespacedName]bool{}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if !r.policySelects(&policy, secret) {
+			continue
+		}
+
+		// Sources with their own annotations, or annotated by another policy, keep their configuration
+		if owner := secret.Annotations[r.annotation(policyKey)]; owner != policy.Name {
+			if _, ok := secret.Annotations[r.annotation(replicationAllowedKey)]; ok {
+				logger.Info(fmt.Sprintf("warning: replication policy %s selects secret %s/%s, which already has a %s annotation", policy.Name, secret.Namespace, secret.Name, r.annotation(replicationAllowedKey)))
+				continue
+			}
+		}
+
+		if err := r.annotateSource(ctx, secret, annotations); err != nil {
+			policyErrs = append(policyErrs, err)
+			continue
+		}
+		applied[client.ObjectKeyFromObject(secret)] = true
+	}
+
+	if err := r.releaseSources(ctx, policy.Name, applied); err != nil {
+		policyErrs = append(policyErrs, err)
+	}
+
+	if err := r.updatePolicyStatus(ctx, &policy, applied); err != nil {
+		policyErrs = append(policyErrs, err)
+	}
+
+	return ctrl.Result{}, utilerrors.NewAggregate(policyErrs)
+}
+
+// policyAnnotations Build the source annotations equivalent to a policy
+func (r *ReplicationPolicyReconciler) policyAnnotations(policy *v1alpha1.ReplicationPolicy) (map[string]string, error) {
+	if _, err := metav1.LabelSelectorAsSelector(&policy.Spec.Selector); err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+
+	annotations := map[string]string{
+		r.annotation(replicationAllowedKey): "true",
+		r.annotation(policyKey):             policy.Name,
+	}
+	if len(policy.Spec.TargetNamespaces) > 0 {
+		annotations[r.annotation(allowedNamespacesKey)] = strings.Join(policy.Spec.TargetNamespaces, ",")
+	}
+	if len(policy.Spec.ExcludedNamespaces) > 0 {
+		annotations[r.annotation(excludedNamespacesKey)] = strings.Join(policy.Spec.ExcludedNamespaces, ",")
+	}
+	if policy.Spec.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(policy.Spec.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespaceSelector: %w", err)
+		}
+		annotations[r.annotation(namespaceSelectorKey)] = selector.String()
+	}
+	return annotations, nil
+}
+
+// annotateSource Replace the policy annotations of a source, skipped if they didn't change
+func (r *ReplicationPolicyReconciler) annotateSource(ctx context.Context, secret *v1.Secret, annotations map[string]string) error {
+	logger := log.FromContext(ctx)
+
+	patch := client.MergeFrom(secret.DeepCopy())
+	updated := map[string]string{}
+	for key, value := range secret.Annotations {
+		updated[key] = value
+	}
+	for _, key := range policyAnnotationKeys {
+		delete(updated, r.annotation(key))
+	}
+	for key, value := range annotations {
+		updated[key] = value
+	}
+
+	if reflect.DeepEqual(updated, secret.Annotations) {
+		return nil
+	}
+	secret.Annotations = updated
+
+	if err := r.Patch(ctx, secret, patch); err != nil {
+		logger.Error(err, fmt.Sprintf("error applying replication policy to secret %s/%s", secret.Namespace, secret.Name))
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("applied replication policy %s to secret %s/%s", annotations[r.annotation(policyKey)], secret.Namespace, secret.Name))
+	return nil
+}
+
+// releaseSources Remove the policy annotations of the sources a policy no longer applies to, replication of the
+// source stops like when its replication-allowed annotation is removed
+func (r *ReplicationPolicyReconciler) releaseSources(ctx context.Context, policyName string, applied map[types.NamespacedName]bool) error {
+	logger := log.FromContext(ctx)
+
+	var secrets v1.SecretList
+	if err := r.List(ctx, &secrets, client.MatchingFields{sourcePolicyField: policyName}); err != nil {
+		logger.Error(err, fmt.Sprintf("error listing the sources of replication policy %s", policyName))
+		return err
+	}
+
+	releaseErrs := []error{}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if applied[client.ObjectKeyFromObject(secret)] {
+			continue
+		}
+
+		patch := client.MergeFrom(secret.DeepCopy())
+		for _, key := range policyAnnotationKeys {
+			delete(secret.Annotations, r.annotation(key))
+		}
+		if err := r.Patch(ctx, secret, patch); err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, fmt.Sprintf("error releasing secret %s/%s from replication policy %s", secret.Namespace, secret.Name, policyName))
+			releaseErrs = append(releaseErrs, err)
+			continue
+		}
+		logger.Info(fmt.Sprintf("released secret %s/%s from replication policy %s", secret.Namespace, secret.Name, policyName))
+	}
+	return utilerrors.NewAggregate(releaseErrs)
+}
+
+// updatePolicyStatus List the sources a policy applies to in its status, skipped if they didn't change
+func (r *ReplicationPolicyReconciler) updatePolicyStatus(ctx context.Context, policy *v1alpha1.ReplicationPolicy, applied map[types.NamespacedName]bool) error {
+	sources := []string{}
+	for source := range applied {
+		sources = append(sources, source.String())
+	}
+	sort.Strings(sources)
+
+	if reflect.DeepEqual(sources, policy.Status.Sources) || (len(sources) == 0 && len(policy.Status.Sources) == 0) {
+		return nil
+	}
+
+	policy.Status.Sources = sources
+	if err := r.Status().Update(ctx, policy); err != nil {
+		log.FromContext(ctx).Error(err, fmt.Sprintf("error updating status of replication policy %s", policy.Name))
+		return err
+	}
+	return nil
+}
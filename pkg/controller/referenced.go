@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// skipUnreferencedNamespaces Keep the target namespaces with a service account or pod referencing the replica in its
+// imagePullSecrets, if the source sets replicate-if-referenced. Replicas in the other namespaces are orphaned. The
+// service accounts and pods are read with an uncached reader, one target namespace at a time, so the controller never
+// caches the pods of the cluster.
+func (r *SecretReconciler) skipUnreferencedNamespaces(ctx context.Context, c client.Reader, secret *v1.Secret, namespaces []string) ([]string, error) {
+	logger := log.FromContext(ctx)
+
+	if !r.replicateIfReferenced(secret) {
+		return namespaces, nil
+	}
+
+	targetName := r.getTargetName(secret)
+	filtered := []string{}
+	for _, namespace := range namespaces {
+		referenced, err := namespaceReferencesPullSecret(ctx, c, namespace, targetName)
+		if err != nil {
+			return nil, err
+		}
+
+		if !referenced {
+			logger.Info(fmt.Sprintf("not replicating %s to namespace %s, no service account or pod in namespace %s references %s", secret.Name, namespace, namespace, targetName))
+			replicationSummaryFrom(ctx).exclude()
+			continue
+		}
+		filtered = append(filtered, namespace)
+	}
+	return filtered, nil
+}
+
+// namespaceReferencesPullSecret Check if a service account or pod of a namespace lists a secret in its imagePullSecrets,
+// service accounts are checked first as workloads usually reference pull secrets through them
+func namespaceReferencesPullSecret(ctx context.Context, c client.Reader, namespace string, name string) (bool, error) {
+	var serviceAccounts v1.ServiceAccountList
+	if err := c.List(ctx, &serviceAccounts, client.InNamespace(namespace)); err != nil {
+		return false, err
+	}
+	for _, serviceAccount := range serviceAccounts.Items {
+		if referencesPullSecret(serviceAccount.ImagePullSecrets, name) {
+			return true, nil
+		}
+	}
+
+	var pods v1.PodList
+	if err := c.List(ctx, &pods, client.InNamespace(namespace)); err != nil {
+		return false, err
+	}
+	for _, pod := range pods.Items {
+		if referencesPullSecret(pod.Spec.ImagePullSecrets, name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// referencesPullSecret Check if a list of imagePullSecrets references a secret name
+func referencesPullSecret(pullSecrets []v1.LocalObjectReference, name string) bool {
+	for _, pullSecret := range pullSecrets {
+		if pullSecret.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceAccountToSources Enqueue the managed sources with replicate-if-referenced whose replicas a service account
+// references. Service accounts are watched, and cached, for this; pods aren't watched and are picked up by the next
+// reconcile of their source.
+func (r *SecretReconciler) serviceAccountToSources(ctx context.Context, obj client.Object) []reconcile.Request {
+	serviceAccount, ok := obj.(*v1.ServiceAccount)
+	if !ok || len(serviceAccount.ImagePullSecrets) == 0 {
+		return nil
+	}
+
+	sources := []types.NamespacedName{}
+	for _, source := range r.SecretList.Items() {
+		var secret v1.Secret
+		if err := r.Get(ctx, source, &secret); err != nil {
+			continue
+		}
+
+		if r.replicateIfReferenced(&secret) && referencesPullSecret(serviceAccount.ImagePullSecrets, r.getTargetName(&secret)) {
+			sources = append(sources, source)
+		}
+	}
+	return requestsFor(sources)
+}
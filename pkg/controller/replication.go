@@ -0,0 +1,1459 @@
+package controller
+
+import (
+	"com.dm0275/secret-replicator-controller/pkg/encrypt"
+	"com.dm0275/secret-replicator-controller/pkg/transform"
+	"com.dm0275/secret-replicator-controller/utils"
+	"context"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	"math/rand"
+	"path"
+	"reflect"
+	"regexp"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	replicatedFromKey             = "replicated-from"
+	replicationAllowedKey         = "replication-allowed"
+	allowedNamespacesKey          = "allowed-namespaces"
+	replicateToAllKey             = "replicate-to-all"
+	excludedNamespacesKey         = "excluded-namespaces"
+	excludedNamespacesRegexKey    = "excluded-namespaces-regex"
+	reconciliationIntervalKey     = "reconcile-interval"
+	namespaceSelectorKey          = "namespace-selector"
+	allowProtectedKey             = "allow-protected-namespaces"
+	targetNameKey                 = "target-name"
+	includeKeysKey                = "include-keys"
+	excludeKeysKey                = "exclude-keys"
+	statusKey                     = "status"
+	dataHashKey                   = "data-hash"
+	immutableReplicasKey          = "immutable-replicas"
+	pausedKey                     = "paused"
+	targetClusterKey              = "target-cluster"
+	replicasKey                   = "replicas"
+	overwriteExistingKey          = "overwrite-existing"
+	matchNamespaceAnnotationKey   = "match-namespace-annotation"
+	transformKey                  = "transform"
+	transformKeysKey              = "transform-keys"
+	mergeTargetKey                = "merge-target"
+	mergedFromKey                 = "merged-from"
+	allowedNamespacesFromKey      = "allowed-namespaces-from"
+	allowedNamespacesRegexKey     = "allowed-namespaces-regex"
+	cleanupOnDeleteKey            = "cleanup-on-delete"
+	templateKey                   = "template"
+	allowEmptyKey                 = "allow-empty"
+	oneShotKey                    = "one-shot"
+	oneShotCompletedKey           = "one-shot-completed"
+	requireNamespaceOptinKey      = "require-namespace-optin"
+	acceptKey                     = "accept"
+	excludeNamespacesWithLabelKey = "exclude-namespaces-with-label"
+	overridesFromKey              = "overrides-from"
+	optOutKey                     = "opt-out"
+	replicatePlaceholderKey       = "replicate-placeholder"
+	replicaLabelsKey              = "replica-labels"
+	encryptWithKey                = "encrypt-with"
+	plaintextHashKey              = "plaintext-hash"
+	copyAnnotationsPrefixKey      = "copy-annotations-prefix"
+	rolloutKey                    = "rollout"
+	canaryNamespaceKey            = "canary-namespace"
+	rolloutPromoteKey             = "rollout-promote"
+	rolloutStatusKey              = "rollout-status"
+	policyKey                     = "policy"
+	renameKeysKey                 = "rename-keys"
+	replicateIfReferencedKey      = "replicate-if-referenced"
+	finalizerKey                  = "finalizer"
+	observedKey                   = "observed"
+)
+
+// controlAnnotations Replicator annotations that are never copied from a source to its replicas
+var controlAnnotations = []string{
+	replicatedFromKey,
+	replicationAllowedKey,
+	allowedNamespacesKey,
+	replicateToAllKey,
+	excludedNamespacesKey,
+	excludedNamespacesRegexKey,
+	namespaceSelectorKey,
+	allowProtectedKey,
+	targetNameKey,
+	includeKeysKey,
+	excludeKeysKey,
+	statusKey,
+	dataHashKey,
+	immutableReplicasKey,
+	pausedKey,
+	targetClusterKey,
+	replicasKey,
+	overwriteExistingKey,
+	matchNamespaceAnnotationKey,
+	transformKey,
+	transformKeysKey,
+	mergeTargetKey,
+	mergedFromKey,
+	allowedNamespacesFromKey,
+	allowedNamespacesRegexKey,
+	cleanupOnDeleteKey,
+	templateKey,
+	allowEmptyKey,
+	oneShotKey,
+	oneShotCompletedKey,
+	requireNamespaceOptinKey,
+	excludeNamespacesWithLabelKey,
+	overridesFromKey,
+	replicatePlaceholderKey,
+	replicaLabelsKey,
+	encryptWithKey,
+	plaintextHashKey,
+	copyAnnotationsPrefixKey,
+	rolloutKey,
+	canaryNamespaceKey,
+	rolloutPromoteKey,
+	rolloutStatusKey,
+	policyKey,
+	renameKeysKey,
+	replicateIfReferencedKey,
+	reconciliationIntervalKey,
+	observedKey,
+}
+
+// DefaultAnnotationPrefix Prefix of the annotations the controller reads and writes
+var DefaultAnnotationPrefix = "secret-replicator.fussionlabs.com"
+
+// DefaultReconcileInterval Reconcile interval of sources without a reconcile-interval annotation
+var DefaultReconcileInterval = time.Duration(5 * time.Minute)
+
+// DefaultMinReconcileInterval Lowest reconcile interval a source can request
+var DefaultMinReconcileInterval = time.Duration(30 * time.Second)
+
+// DefaultReconcileJitter Fraction the reconcile interval is randomly shortened or lengthened by
+var DefaultReconcileJitter = 0.1
+
+var (
+	// SyncModePeriodic Reconcile sources on watch events and every reconcile interval
+	SyncModePeriodic = "periodic"
+	// SyncModeWatchOnly Only reconcile sources on watch events, sources aren't requeued
+	SyncModeWatchOnly = "watch-only"
+)
+
+// DefaultProtectedNamespaces Namespaces that are never replicated to unless explicitly allowed
+var DefaultProtectedNamespaces = "kube-system,kube-public,kube-node-lease"
+
+// ReplicationOptions Controller-wide replication settings shared by the reconcilers
+type ReplicationOptions struct {
+	// AnnotationPrefix Prefix of the replicator annotations, defaults to DefaultAnnotationPrefix
+	AnnotationPrefix string
+	// ProtectedNamespaces Namespaces excluded from replication by default. A source can only
+	// replicate to them by listing them in allowed-namespaces and setting allow-protected-namespaces.
+	ProtectedNamespaces []string
+	// StrictNamespaceValidation Fail validation when allowed-namespaces refers to namespaces that don't exist
+	StrictNamespaceValidation bool
+	// RequireExplicitAllNamespaces Only replicate to all namespaces when a source sets allowed-namespaces
+	// to "*" or replicate-to-all, an empty allowed-namespaces then means no replication
+	RequireExplicitAllNamespaces bool
+	// DefaultReconcileInterval Reconcile interval of sources without a reconcile-interval annotation,
+	// defaults to DefaultReconcileInterval
+	DefaultReconcileInterval time.Duration
+	// MinReconcileInterval Lowest reconcile interval a source can request, lower values are raised to it
+	MinReconcileInterval time.Duration
+	// GlobalAllowedNamespaces Namespaces any source can be replicated to, entries can be glob patterns.
+	// Target namespaces outside of the list are skipped, an empty list allows all namespaces.
+	GlobalAllowedNamespaces []string
+	// ReplicationConcurrency Number of target namespaces a source is replicated to in parallel, defaults to 1
+	ReplicationConcurrency int
+	// NamespacePageSize Number of namespaces fetched per List call when computing target namespaces,
+	// 0 lists all namespaces at once. Paginated lists are read from the API server, the cache doesn't support them.
+	NamespacePageSize int64
+	// SyncMode SyncModePeriodic or SyncModeWatchOnly, defaults to SyncModePeriodic
+	SyncMode string
+	// MaxTargetNamespaces Highest number of target namespaces a source can be replicated to, sources with more
+	// targets aren't replicated. 0 disables the limit.
+	MaxTargetNamespaces int
+	// ReconcileJitter Fraction the reconcile interval is randomly shortened or lengthened by, so the requeues of
+	// sources sharing an interval don't align. 0 disables the jitter.
+	ReconcileJitter float64
+	// DeniedSecretTypes Secret types that are never replicated, even if their source is annotated
+	DeniedSecretTypes []string
+	// ForeignManagerAnnotations Annotation keys of other tools managing secrets, existing target secrets carrying any
+	// of them are left alone
+	ForeignManagerAnnotations []string
+	// TLSExpiryWarning Window before the certificate of a TLS source secret expires in which a warning event is
+	// recorded on the source, 0 disables the warning
+	TLSExpiryWarning time.Duration
+	// NewNamespaceDelay Time after their creation before namespaces are replicated to, so they are set up by other
+	// controllers first. 0 replicates to new namespaces immediately.
+	NewNamespaceDelay time.Duration
+	// KillSwitch ConfigMap disabling all replication while its enabled key is false, an empty name disables the
+	// kill switch
+	KillSwitch types.NamespacedName
+	// CoalesceWindow Delay of the enqueues fanned out from namespace and configmap events, repeated enqueues of a
+	// source within it are coalesced into one reconcile. 0 enqueues immediately.
+	CoalesceWindow time.Duration
+	// JitterSource Returns random numbers in [0.0,1.0) used for the reconcile jitter, defaults to rand.Float64
+	JitterSource func() float64
+}
+
+// managedByLabel Label set on every replica naming the controller that manages it
+var managedByLabel = "app.kubernetes.io/managed-by"
+
+// managedByValue Value of the managed-by label of replicas
+var managedByValue = "secret-replicator"
+
+// fieldManager Field manager of the replicas written with server-side apply
+var fieldManager = "secret-replicator"
+
+// lastAppliedConfigAnnotation Set by kubectl apply, describes the source object so it isn't copied to replicas
+var lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+var (
+	eventReasonReplicated          = "Replicated"
+	eventReasonUpdated             = "Updated"
+	eventReasonSkippedExcluded     = "SkippedExcluded"
+	eventReasonReplicationFailed   = "ReplicationFailed"
+	eventReasonNamespaceNotFound   = "NamespaceNotFound"
+	eventReasonDryRun              = "DryRun"
+	eventReasonConflictSkipped     = "ConflictSkipped"
+	eventReasonSkippedNotAllowed   = "SkippedNotAllowed"
+	eventReasonMergeConflict       = "MergeConflict"
+	eventReasonConfigMapNotFound   = "ConfigMapNotFound"
+	eventReasonTemplateFailed      = "TemplateFailed"
+	eventReasonSkippedEmpty        = "SkippedEmpty"
+	eventReasonTooManyTargets      = "TooManyTargets"
+	eventReasonCertificateExpiring = "CertificateExpiring"
+	eventReasonSkippedDeniedType   = "SkippedDeniedType"
+	eventReasonSecretTooLarge      = "SecretTooLarge"
+)
+
+// annotation Build the full name of a replicator annotation
+func (o ReplicationOptions) annotation(key string) string {
+	prefix := o.AnnotationPrefix
+	if prefix == "" {
+		prefix = DefaultAnnotationPrefix
+	}
+	return fmt.Sprintf("%s/%s", prefix, key)
+}
+
+// replicateEnabled Check if an object is a replication source. Replicas are never sources, whatever their other
+// annotations, so replicas can't be replicated in a loop.
+func (o ReplicationOptions) replicateEnabled(obj metav1.Object) bool {
+	if _, ok := o.getReplicatedFrom(obj); ok {
+		return false
+	}
+
+	replicationAllowed, ok := obj.GetAnnotations()[o.annotation(replicationAllowedKey)]
+	if !ok {
+		return false
+	}
+
+	replicationAllowedBool, err := strconv.ParseBool(replicationAllowed)
+	if err != nil {
+		return false
+	}
+
+	return replicationAllowedBool
+}
+
+// overwriteExisting Check if a source opted in to overwriting existing objects that aren't replicas
+func (o ReplicationOptions) overwriteExisting(obj metav1.Object) bool {
+	overwriteExisting, ok := obj.GetAnnotations()[o.annotation(overwriteExistingKey)]
+	if !ok {
+		return false
+	}
+
+	overwriteExistingBool, err := strconv.ParseBool(overwriteExisting)
+	if err != nil {
+		return false
+	}
+
+	return overwriteExistingBool
+}
+
+// cleanupOnDelete Check if the replicas of a source are deleted with it, defaults to true
+func (o ReplicationOptions) cleanupOnDelete(obj metav1.Object) bool {
+	cleanupOnDelete, ok := obj.GetAnnotations()[o.annotation(cleanupOnDeleteKey)]
+	if !ok {
+		return true
+	}
+
+	cleanupOnDeleteBool, err := strconv.ParseBool(cleanupOnDelete)
+	if err != nil {
+		return true
+	}
+
+	return cleanupOnDeleteBool
+}
+
+// templateEnabled Check if the data values of a source are rendered as templates for each target namespace
+func (o ReplicationOptions) templateEnabled(obj metav1.Object) bool {
+	templateEnabled, ok := obj.GetAnnotations()[o.annotation(templateKey)]
+	if !ok {
+		return false
+	}
+
+	templateEnabledBool, err := strconv.ParseBool(templateEnabled)
+	if err != nil {
+		return false
+	}
+
+	return templateEnabledBool
+}
+
+// allowEmpty Check if a source without any data may be replicated
+func (o ReplicationOptions) allowEmpty(obj metav1.Object) bool {
+	allowEmpty, ok := obj.GetAnnotations()[o.annotation(allowEmptyKey)]
+	if !ok {
+		return false
+	}
+
+	allowEmptyBool, err := strconv.ParseBool(allowEmpty)
+	if err != nil {
+		return false
+	}
+
+	return allowEmptyBool
+}
+
+// replicatePlaceholder Check if a source without data is replicated as placeholders with empty data
+func (o ReplicationOptions) replicatePlaceholder(obj metav1.Object) bool {
+	replicatePlaceholder, ok := obj.GetAnnotations()[o.annotation(replicatePlaceholderKey)]
+	if !ok {
+		return false
+	}
+
+	replicatePlaceholderBool, err := strconv.ParseBool(replicatePlaceholder)
+	if err != nil {
+		return false
+	}
+
+	return replicatePlaceholderBool
+}
+
+// replicateIfReferenced Check if a source is only replicated to namespaces referencing it in imagePullSecrets
+func (o ReplicationOptions) replicateIfReferenced(obj metav1.Object) bool {
+	replicateIfReferenced, ok := obj.GetAnnotations()[o.annotation(replicateIfReferencedKey)]
+	if !ok {
+		return false
+	}
+
+	replicateIfReferencedBool, err := strconv.ParseBool(replicateIfReferenced)
+	if err != nil {
+		return false
+	}
+
+	return replicateIfReferencedBool
+}
+
+// oneShot Check if a source is replicated once and then left alone
+func (o ReplicationOptions) oneShot(obj metav1.Object) bool {
+	oneShot, ok := obj.GetAnnotations()[o.annotation(oneShotKey)]
+	if !ok {
+		return false
+	}
+
+	oneShotBool, err := strconv.ParseBool(oneShot)
+	if err != nil {
+		return false
+	}
+
+	return oneShotBool
+}
+
+// oneShotCompleted Check if a one-shot source was already fully replicated
+func (o ReplicationOptions) oneShotCompleted(obj metav1.Object) bool {
+	return o.oneShot(obj) && obj.GetAnnotations()[o.annotation(oneShotCompletedKey)] == "true"
+}
+
+// requireNamespaceOptin Check if a source is only replicated to namespaces carrying the accept label
+func (o ReplicationOptions) requireNamespaceOptin(obj metav1.Object) bool {
+	requireNamespaceOptin, ok := obj.GetAnnotations()[o.annotation(requireNamespaceOptinKey)]
+	if !ok {
+		return false
+	}
+
+	requireNamespaceOptinBool, err := strconv.ParseBool(requireNamespaceOptin)
+	if err != nil {
+		return false
+	}
+
+	return requireNamespaceOptinBool
+}
+
+// replicationPaused Check if replication of an enabled source is temporarily paused, its replicas are left untouched
+func (o ReplicationOptions) replicationPaused(obj metav1.Object) bool {
+	paused, ok := obj.GetAnnotations()[o.annotation(pausedKey)]
+	if !ok {
+		return false
+	}
+
+	pausedBool, err := strconv.ParseBool(paused)
+	if err != nil {
+		return false
+	}
+
+	return pausedBool
+}
+
+// protectedNamespacesAllowed Check if a source opted in to replicating to explicitly allowed protected namespaces
+func (o ReplicationOptions) protectedNamespacesAllowed(obj metav1.Object) bool {
+	allowProtected, ok := obj.GetAnnotations()[o.annotation(allowProtectedKey)]
+	if !ok {
+		return false
+	}
+
+	allowProtectedBool, err := strconv.ParseBool(allowProtected)
+	if err != nil {
+		return false
+	}
+
+	return allowProtectedBool
+}
+
+// replicateToAll Check if a source explicitly asked to be replicated to all namespaces
+func (o ReplicationOptions) replicateToAll(obj metav1.Object) bool {
+	replicateToAll, ok := obj.GetAnnotations()[o.annotation(replicateToAllKey)]
+	if !ok {
+		return false
+	}
+
+	replicateToAllBool, err := strconv.ParseBool(replicateToAll)
+	if err != nil {
+		return false
+	}
+
+	return replicateToAllBool
+}
+
+// immutableReplicas Check if a source asked for its replicas to be created immutable
+func (o ReplicationOptions) immutableReplicas(obj metav1.Object) bool {
+	immutableReplicas, ok := obj.GetAnnotations()[o.annotation(immutableReplicasKey)]
+	if !ok {
+		return false
+	}
+
+	immutableReplicasBool, err := strconv.ParseBool(immutableReplicas)
+	if err != nil {
+		return false
+	}
+
+	return immutableReplicasBool
+}
+
+func (o ReplicationOptions) getAllowedNamespaces(obj metav1.Object) []string {
+	allowedNamespaces, ok := obj.GetAnnotations()[o.annotation(allowedNamespacesKey)]
+	if !ok {
+		return []string{}
+	}
+
+	return strings.Split(allowedNamespaces, ",")
+}
+
+func (o ReplicationOptions) getExcludedNamespaces(obj metav1.Object) []string {
+	excludedNamespaces, ok := obj.GetAnnotations()[o.annotation(excludedNamespacesKey)]
+	if !ok {
+		return []string{}
+	}
+
+	return strings.Split(excludedNamespaces, ",")
+}
+
+// getExcludeNamespacesWithLabel Label keys marking namespaces as excluded regardless of the label value
+func (o ReplicationOptions) getExcludeNamespacesWithLabel(obj metav1.Object) []string {
+	excludeLabels, ok := obj.GetAnnotations()[o.annotation(excludeNamespacesWithLabelKey)]
+	if !ok || excludeLabels == "" {
+		return []string{}
+	}
+
+	return strings.Split(excludeLabels, ",")
+}
+
+// hasAnyLabel Check if an object has any of the label keys, whatever their value
+func hasAnyLabel(obj metav1.Object, keys []string) bool {
+	for _, key := range keys {
+		if _, ok := obj.GetLabels()[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// getExcludedNamespacesRegex Compile the excluded-namespaces-regex annotation, returns nil if it isn't set
+func (o ReplicationOptions) getExcludedNamespacesRegex(obj metav1.Object) (*regexp.Regexp, error) {
+	excludedRegex, ok := obj.GetAnnotations()[o.annotation(excludedNamespacesRegexKey)]
+	if !ok {
+		return nil, nil
+	}
+
+	return regexp.Compile(excludedRegex)
+}
+
+// getAllowedNamespacesRegex Compile the allowed-namespaces-regex annotation, returns nil if it isn't set
+func (o ReplicationOptions) getAllowedNamespacesRegex(obj metav1.Object) (*regexp.Regexp, error) {
+	allowedRegex, ok := obj.GetAnnotations()[o.annotation(allowedNamespacesRegexKey)]
+	if !ok {
+		return nil, nil
+	}
+
+	return regexp.Compile(allowedRegex)
+}
+
+// getNamespaceSelector Parse the namespace-selector annotation, returns nil if it isn't set
+func (o ReplicationOptions) getNamespaceSelector(obj metav1.Object) (labels.Selector, error) {
+	namespaceSelector, ok := obj.GetAnnotations()[o.annotation(namespaceSelectorKey)]
+	if !ok {
+		return nil, nil
+	}
+
+	return labels.Parse(namespaceSelector)
+}
+
+// getIncludeKeys Data keys to replicate, an empty list means all keys
+func (o ReplicationOptions) getIncludeKeys(obj metav1.Object) []string {
+	includeKeys, ok := obj.GetAnnotations()[o.annotation(includeKeysKey)]
+	if !ok {
+		return []string{}
+	}
+
+	return strings.Split(includeKeys, ",")
+}
+
+// getExcludeKeys Data keys that are not replicated
+func (o ReplicationOptions) getExcludeKeys(obj metav1.Object) []string {
+	excludeKeys, ok := obj.GetAnnotations()[o.annotation(excludeKeysKey)]
+	if !ok {
+		return []string{}
+	}
+
+	return strings.Split(excludeKeys, ",")
+}
+
+// getTransform Get the transform applied to replicated data keys, returns nil if it isn't set
+func (o ReplicationOptions) getTransform(obj metav1.Object) (transform.Transform, error) {
+	name, ok := obj.GetAnnotations()[o.annotation(transformKey)]
+	if !ok || name == "" {
+		return nil, nil
+	}
+
+	return transform.Get(strings.TrimSpace(name))
+}
+
+// getEncryptor Build the encryptor of the encrypt-with annotation, returns nil if it isn't set
+func (o ReplicationOptions) getEncryptor(obj metav1.Object) (encrypt.Encryptor, error) {
+	spec, ok := obj.GetAnnotations()[o.annotation(encryptWithKey)]
+	if !ok || spec == "" {
+		return nil, nil
+	}
+
+	return encrypt.Get(spec)
+}
+
+// getTransformKeys Data keys the transform is applied to, an empty list means all replicated keys
+func (o ReplicationOptions) getTransformKeys(obj metav1.Object) []string {
+	transformKeys, ok := obj.GetAnnotations()[o.annotation(transformKeysKey)]
+	if !ok {
+		return []string{}
+	}
+
+	return strings.Split(transformKeys, ",")
+}
+
+// projectKeys Select the data keys replicated from a source
+func (o ReplicationOptions) projectKeys(obj metav1.Object, keys []string) []string {
+	includeKeys := o.getIncludeKeys(obj)
+	excludeKeys := o.getExcludeKeys(obj)
+
+	projected := []string{}
+	for _, key := range keys {
+		if len(includeKeys) > 0 && !utils.ListContains(includeKeys, key) {
+			continue
+		} else if utils.ListContains(excludeKeys, key) {
+			continue
+		}
+		projected = append(projected, key)
+	}
+
+	return projected
+}
+
+// getTargetName Name of the replicas of a source object, defaults to the source name
+func (o ReplicationOptions) getTargetName(obj metav1.Object) string {
+	targetName, ok := obj.GetAnnotations()[o.annotation(targetNameKey)]
+	if !ok || targetName == "" {
+		return obj.GetName()
+	}
+
+	return targetName
+}
+
+// getMatchNamespaceAnnotation Parse the key=value match-namespace-annotation annotation, the key is empty if it isn't set
+func (o ReplicationOptions) getMatchNamespaceAnnotation(obj metav1.Object) (string, string, error) {
+	match, ok := obj.GetAnnotations()[o.annotation(matchNamespaceAnnotationKey)]
+	if !ok || match == "" {
+		return "", "", nil
+	}
+
+	key, value, found := strings.Cut(match, "=")
+	key = strings.TrimSpace(key)
+	if !found || key == "" {
+		return "", "", fmt.Errorf("%s is not a key=value pair", match)
+	}
+
+	return key, strings.TrimSpace(value), nil
+}
+
+// getReplicaLabels Parse the key1=value1,key2=value2 replica-labels annotation into the extra labels of the replicas
+func (o ReplicationOptions) getReplicaLabels(obj metav1.Object) (map[string]string, error) {
+	labels := map[string]string{}
+	replicaLabels, ok := obj.GetAnnotations()[o.annotation(replicaLabelsKey)]
+	if !ok || replicaLabels == "" {
+		return labels, nil
+	}
+
+	for _, label := range strings.Split(replicaLabels, ",") {
+		key, value, found := strings.Cut(label, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !found || key == "" {
+			return nil, fmt.Errorf("%s is not a key=value pair", label)
+		}
+
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid label key %s: %s", key, strings.Join(errs, ", "))
+		} else if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid value of label %s: %s", key, strings.Join(errs, ", "))
+		}
+		labels[key] = value
+	}
+
+	return labels, nil
+}
+
+// getRenameKeys Parse the oldKey=newKey rename-keys annotation into the data keys renamed on replicas, two source keys
+// can't be renamed to the same key
+func (o ReplicationOptions) getRenameKeys(obj metav1.Object) (map[string]string, error) {
+	renames := map[string]string{}
+	renameKeys, ok := obj.GetAnnotations()[o.annotation(renameKeysKey)]
+	if !ok || renameKeys == "" {
+		return renames, nil
+	}
+
+	targets := map[string]string{}
+	for _, rename := range strings.Split(renameKeys, ",") {
+		oldKey, newKey, found := strings.Cut(rename, "=")
+		oldKey, newKey = strings.TrimSpace(oldKey), strings.TrimSpace(newKey)
+		if !found || oldKey == "" || newKey == "" {
+			return nil, fmt.Errorf("%s is not an oldKey=newKey pair", rename)
+		}
+
+		if errs := validation.IsConfigMapKey(newKey); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid key %s: %s", newKey, strings.Join(errs, ", "))
+		} else if source, ok := targets[newKey]; ok {
+			return nil, fmt.Errorf("keys %s and %s are both renamed to %s", source, oldKey, newKey)
+		}
+		targets[newKey] = oldKey
+		renames[oldKey] = newKey
+	}
+
+	return renames, nil
+}
+
+// defaultAllowedNamespacesFromKey ConfigMap key read by allowed-namespaces-from when the reference doesn't name one
+var defaultAllowedNamespacesFromKey = "allowed-namespaces"
+
+// getAllowedNamespacesFrom Parse the namespace/name[key] allowed-namespaces-from annotation into the ConfigMap
+// and key the allowed namespaces are read from, returns false if it isn't set
+func (o ReplicationOptions) getAllowedNamespacesFrom(obj metav1.Object) (types.NamespacedName, string, bool, error) {
+	allowedNamespacesFrom, ok := obj.GetAnnotations()[o.annotation(allowedNamespacesFromKey)]
+	if !ok || allowedNamespacesFrom == "" {
+		return types.NamespacedName{}, "", false, nil
+	}
+
+	reference := strings.TrimSpace(allowedNamespacesFrom)
+	key := defaultAllowedNamespacesFromKey
+	if strings.HasSuffix(reference, "]") {
+		start := strings.LastIndex(reference, "[")
+		if start < 0 {
+			return types.NamespacedName{}, "", false, fmt.Errorf("%s is not a namespace/name[key] reference", allowedNamespacesFrom)
+		}
+		reference, key = reference[:start], reference[start+1:len(reference)-1]
+	}
+
+	namespace, name, found := strings.Cut(reference, "/")
+	if !found || namespace == "" || name == "" || key == "" {
+		return types.NamespacedName{}, "", false, fmt.Errorf("%s is not a namespace/name[key] reference", allowedNamespacesFrom)
+	}
+
+	return types.NamespacedName{Namespace: namespace, Name: name}, key, true, nil
+}
+
+// getMergeTarget Parse the namespace/name merge-target annotation, returns false if it isn't set
+func (o ReplicationOptions) getMergeTarget(obj metav1.Object) (types.NamespacedName, bool, error) {
+	mergeTarget, ok := obj.GetAnnotations()[o.annotation(mergeTargetKey)]
+	if !ok || mergeTarget == "" {
+		return types.NamespacedName{}, false, nil
+	}
+
+	namespace, name, found := strings.Cut(strings.TrimSpace(mergeTarget), "/")
+	if !found || namespace == "" || name == "" {
+		return types.NamespacedName{}, false, fmt.Errorf("%s is not a namespace/name pair", mergeTarget)
+	}
+
+	return types.NamespacedName{Namespace: namespace, Name: name}, true, nil
+}
+
+// getOverridesFrom Parse the namespace/name overrides-from annotation into the ConfigMap of per-namespace data
+// overrides, returns false if it isn't set
+func (o ReplicationOptions) getOverridesFrom(obj metav1.Object) (types.NamespacedName, bool, error) {
+	overridesFrom, ok := obj.GetAnnotations()[o.annotation(overridesFromKey)]
+	if !ok || overridesFrom == "" {
+		return types.NamespacedName{}, false, nil
+	}
+
+	namespace, name, found := strings.Cut(strings.TrimSpace(overridesFrom), "/")
+	if !found || namespace == "" || name == "" {
+		return types.NamespacedName{}, false, fmt.Errorf("%s is not a namespace/name pair", overridesFrom)
+	}
+
+	return types.NamespacedName{Namespace: namespace, Name: name}, true, nil
+}
+
+// getTargetCluster Get the name of the remote cluster a source is replicated to, empty for the local cluster
+func (o ReplicationOptions) getTargetCluster(obj metav1.Object) string {
+	return strings.TrimSpace(obj.GetAnnotations()[o.annotation(targetClusterKey)])
+}
+
+func (o ReplicationOptions) validateConfiguration(ctx context.Context, c client.Client, recorder record.EventRecorder, obj client.Object) error {
+	if err := o.validateAnnotations(obj); err != nil {
+		return err
+	}
+
+	return o.validateAllowedNamespacesExist(ctx, c, recorder, obj)
+}
+
+// ValidateSource Check the replication annotations of a source before it is admitted. Unlike reconciliation,
+// which falls back to the default interval, an invalid reconcile-interval is rejected.
+func (o ReplicationOptions) ValidateSource(obj metav1.Object) error {
+	if err := o.validateAnnotations(obj); err != nil {
+		return err
+	}
+
+	if interval, ok := obj.GetAnnotations()[o.annotation(reconciliationIntervalKey)]; ok {
+		if _, err := time.ParseDuration(interval); err != nil {
+			return fmt.Errorf("unable to replicate %s, invalid reconcileInterval: %w", obj.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+// validateAnnotations Check the replication annotations of a source without reading from the cluster
+func (o ReplicationOptions) validateAnnotations(obj metav1.Object) error {
+	allowedNamespaces := o.getAllowedNamespaces(obj)
+	excludedNamespaces := o.getExcludedNamespaces(obj)
+
+	if overlap := utils.SlicesIntersection(allowedNamespaces, excludedNamespaces); len(overlap) > 0 {
+		return fmt.Errorf("unable to replicate %s, cannot have overlaps between allowedNamespaces and excludedNamespaces: %s", obj.GetName(), strings.Join(overlap, ","))
+	}
+
+	_, patterns := utils.SplitGlobPatterns(allowedNamespaces)
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("unable to replicate %s, invalid allowedNamespaces pattern %s: %w", obj.GetName(), pattern, err)
+		}
+	}
+
+	if _, err := o.getAllowedNamespacesRegex(obj); err != nil {
+		return fmt.Errorf("unable to replicate %s, invalid allowedNamespacesRegex: %w", obj.GetName(), err)
+	}
+
+	if _, err := o.getExcludedNamespacesRegex(obj); err != nil {
+		return fmt.Errorf("unable to replicate %s, invalid excludedNamespacesRegex: %w", obj.GetName(), err)
+	}
+
+	if _, err := o.getNamespaceSelector(obj); err != nil {
+		return fmt.Errorf("unable to replicate %s, invalid namespaceSelector: %w", obj.GetName(), err)
+	}
+
+	if _, _, err := o.getMatchNamespaceAnnotation(obj); err != nil {
+		return fmt.Errorf("unable to replicate %s, invalid matchNamespaceAnnotation: %w", obj.GetName(), err)
+	}
+
+	if _, _, _, err := o.getAllowedNamespacesFrom(obj); err != nil {
+		return fmt.Errorf("unable to replicate %s, invalid allowedNamespacesFrom: %w", obj.GetName(), err)
+	}
+
+	if _, _, err := o.getMergeTarget(obj); err != nil {
+		return fmt.Errorf("unable to replicate %s, invalid mergeTarget: %w", obj.GetName(), err)
+	}
+
+	if _, _, err := o.getOverridesFrom(obj); err != nil {
+		return fmt.Errorf("unable to replicate %s, invalid overridesFrom: %w", obj.GetName(), err)
+	}
+
+	if rollout, ok := obj.GetAnnotations()[o.annotation(rolloutKey)]; ok && rollout != RolloutCanary {
+		return fmt.Errorf("unable to replicate %s, unknown rollout %s", obj.GetName(), rollout)
+	} else if ok && obj.GetAnnotations()[o.annotation(canaryNamespaceKey)] == "" {
+		return fmt.Errorf("unable to replicate %s, the canary rollout requires %s", obj.GetName(), o.annotation(canaryNamespaceKey))
+	}
+
+	if _, err := o.getReplicaLabels(obj); err != nil {
+		return fmt.Errorf("unable to replicate %s, invalid replicaLabels: %w", obj.GetName(), err)
+	}
+
+	if _, err := o.getTransform(obj); err != nil {
+		return fmt.Errorf("unable to replicate %s, invalid transform: %w", obj.GetName(), err)
+	}
+
+	if _, err := o.getRenameKeys(obj); err != nil {
+		return fmt.Errorf("unable to replicate %s, invalid renameKeys: %w", obj.GetName(), err)
+	}
+
+	if _, err := o.getEncryptor(obj); err != nil {
+		return fmt.Errorf("unable to replicate %s, invalid encryptWith: %w", obj.GetName(), err)
+	}
+
+	if len(o.getIncludeKeys(obj)) > 0 && len(o.getExcludeKeys(obj)) > 0 {
+		return fmt.Errorf("unable to replicate %s, cannot set both includeKeys and excludeKeys", obj.GetName())
+	}
+
+	if errs := validation.IsDNS1123Subdomain(o.getTargetName(obj)); len(errs) > 0 {
+		return fmt.Errorf("unable to replicate %s, invalid targetName: %s", obj.GetName(), strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+// validateAllowedNamespacesExist Warn about literal allowed namespaces that don't exist, in strict mode this is an error
+func (o ReplicationOptions) validateAllowedNamespacesExist(ctx context.Context, c client.Client, recorder record.EventRecorder, obj client.Object) error {
+	logger := log.FromContext(ctx)
+
+	literalNamespaces, _ := utils.SplitGlobPatterns(o.getAllowedNamespaces(obj))
+	missingNamespaces := []string{}
+	for _, namespace := range literalNamespaces {
+		err := c.Get(ctx, client.ObjectKey{Name: namespace}, &v1.Namespace{})
+		if errors.IsNotFound(err) {
+			missingNamespaces = append(missingNamespaces, namespace)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if len(missingNamespaces) == 0 {
+		return nil
+	}
+
+	if o.StrictNamespaceValidation {
+		return fmt.Errorf("unable to replicate %s, allowedNamespaces %s do not exist", obj.GetName(), strings.Join(missingNamespaces, ","))
+	}
+
+	logger.Info(fmt.Sprintf("allowed namespaces %s of %s do not exist", strings.Join(missingNamespaces, ","), obj.GetName()))
+	recorder.Eventf(obj, v1.EventTypeWarning, eventReasonNamespaceNotFound, "allowed namespaces %s do not exist", strings.Join(missingNamespaces, ","))
+	return nil
+}
+
+// getReconciliationInterval Get the interval a source is requeued after with the reconcile jitter applied, 0 in
+// watch-only sync mode
+func (o ReplicationOptions) getReconciliationInterval(ctx context.Context, obj metav1.Object) time.Duration {
+	return o.jitter(o.baseReconciliationInterval(ctx, obj))
+}
+
+// jitter Randomly shorten or lengthen an interval by up to the ReconcileJitter fraction
+func (o ReplicationOptions) jitter(interval time.Duration) time.Duration {
+	if o.ReconcileJitter <= 0 || interval == 0 {
+		return interval
+	}
+
+	random := o.JitterSource
+	if random == nil {
+		random = rand.Float64
+	}
+
+	return time.Duration(float64(interval) * (1 + o.ReconcileJitter*(2*random()-1)))
+}
+
+// baseReconciliationInterval Get the interval a source requested, 0 in watch-only sync mode
+func (o ReplicationOptions) baseReconciliationInterval(ctx context.Context, obj metav1.Object) time.Duration {
+	logger := log.FromContext(ctx)
+	if o.SyncMode == SyncModeWatchOnly {
+		return 0
+	}
+
+	defaultInterval := o.defaultReconciliationInterval()
+	reconciliationInterval, ok := obj.GetAnnotations()[o.annotation(reconciliationIntervalKey)]
+	if !ok {
+		return defaultInterval
+	}
+
+	interval, err := time.ParseDuration(reconciliationInterval)
+	if err != nil {
+		logger.Error(err, "invalid reconciliation interval")
+		return defaultInterval
+	}
+
+	// An interval of 0 opts the object into the watch-only sync mode, it is never requeued
+	if interval == 0 {
+		return 0
+	}
+
+	if interval < o.MinReconcileInterval {
+		logger.Info(fmt.Sprintf("reconciliation interval %s of %s is below the minimum, using %s", interval, obj.GetName(), o.MinReconcileInterval))
+		return o.MinReconcileInterval
+	}
+
+	return interval
+}
+
+// defaultReconciliationInterval Get the interval of sources without a reconcile-interval annotation, raised to the
+// MinReconcileInterval
+func (o ReplicationOptions) defaultReconciliationInterval() time.Duration {
+	interval := o.DefaultReconcileInterval
+	if interval == 0 {
+		interval = DefaultReconcileInterval
+	}
+	if interval < o.MinReconcileInterval {
+		return o.MinReconcileInterval
+	}
+	return interval
+}
+
+// getTargetNamespaces Compute the namespaces a source object should be replicated to.
+// When both allowed-namespaces and namespace-selector are set, the selector further
+// filters the allowed list, only allowed namespaces matching the selector are targeted.
+// Glob patterns in allowed-namespaces are expanded against the namespace list and,
+// unlike literal names, are still subject to excluded-namespaces. Protected namespaces
+// are always excluded unless they are literal allowed names and the source opted in.
+// referencedNamespaces are allowed namespaces resolved from allowed-namespaces-from.
+// Sources requiring a namespace opt-in only target namespaces labeled with accept=true, namespaces with any
+// of the exclude-namespaces-with-label keys are never targeted.
+func (o ReplicationOptions) getTargetNamespaces(ctx context.Context, c client.Reader, recorder record.EventRecorder, obj client.Object, referencedNamespaces []string) ([]string, error) {
+	logger := log.FromContext(ctx)
+
+	allowedNamespaces := append(o.getAllowedNamespaces(obj), referencedNamespaces...)
+	if o.replicateToAll(obj) {
+		allowedNamespaces = []string{"*"}
+	}
+
+	literalNamespaces, namespacePatterns := utils.SplitGlobPatterns(allowedNamespaces)
+	if o.isSourceNamespace(obj, obj.GetNamespace()) && utils.ListContains(literalNamespaces, obj.GetNamespace()) {
+		logger.Info(fmt.Sprintf("%s in the %s namespace is a source", obj.GetName(), obj.GetNamespace()))
+		literalNamespaces = utils.RemoveListItem(literalNamespaces, obj.GetNamespace())
+	}
+	if !o.protectedNamespacesAllowed(obj) {
+		literalNamespaces = o.skipProtectedNamespaces(ctx, recorder, obj, literalNamespaces)
+	}
+
+	selector, err := o.getNamespaceSelector(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	matchKey, matchValue, err := o.getMatchNamespaceAnnotation(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	allowedRegex, err := o.getAllowedNamespacesRegex(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	requireOptin := o.requireNamespaceOptin(obj)
+	excludeLabels := o.getExcludeNamespacesWithLabel(obj)
+
+	if len(allowedNamespaces) > 0 && len(namespacePatterns) == 0 && selector == nil && matchKey == "" && allowedRegex == nil && !requireOptin && len(excludeLabels) == 0 {
+		return o.skipIneligibleNamespaces(ctx, c, recorder, obj, literalNamespaces)
+	}
+
+	if len(allowedNamespaces) == 0 && selector == nil && allowedRegex == nil && o.RequireExplicitAllNamespaces {
+		logger.Info(fmt.Sprintf("not replicating %s, no target namespaces are configured", obj.GetName()))
+		return []string{}, nil
+	}
+
+	listOpts := []client.ListOption{}
+	if selector != nil {
+		listOpts = append(listOpts, client.MatchingLabelsSelector{Selector: selector})
+	}
+
+	excludedRegex, err := o.getExcludedNamespacesRegex(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	// Page through the namespaces when NamespacePageSize is set, a page size of 0 lists all namespaces at once
+	targetNamespaces := []string{}
+	excludedNamespaces := append(o.getExcludedNamespaces(obj), o.ProtectedNamespaces...)
+	continueToken := ""
+	for {
+		var namespaces v1.NamespaceList
+		err = c.List(ctx, &namespaces, append(listOpts, client.Limit(o.NamespacePageSize), client.Continue(continueToken))...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, namespace := range namespaces.Items {
+			if value, ok := namespace.Annotations[matchKey]; matchKey != "" && (!ok || value != matchValue) {
+				continue
+			}
+
+			if namespace.Status.Phase == v1.NamespaceTerminating {
+				logger.Info(fmt.Sprintf("not replicating %s to namespace %s, namespace %s is terminating", obj.GetName(), namespace.Name, namespace.Name))
+				continue
+			}
+
+			if o.namespaceOptedOut(&namespace) {
+				logger.Info(fmt.Sprintf("not replicating %s to namespace %s, namespace %s opted out", obj.GetName(), namespace.Name, namespace.Name))
+				recorder.Eventf(obj, v1.EventTypeNormal, eventReasonSkippedExcluded, "not replicating to namespace %s, namespace opted out", namespace.Name)
+				replicationSummaryFrom(ctx).exclude()
+				continue
+			}
+
+			if requireOptin && namespace.Labels[o.annotation(acceptKey)] != "true" {
+				logger.Info(fmt.Sprintf("not replicating %s to namespace %s, namespace %s didn't opt in", obj.GetName(), namespace.Name, namespace.Name))
+				replicationSummaryFrom(ctx).exclude()
+				continue
+			}
+
+			if hasAnyLabel(&namespace, excludeLabels) {
+				logger.Info(fmt.Sprintf("not replicating %s to namespace %s, namespace %s has an excluded label", obj.GetName(), namespace.Name, namespace.Name))
+				recorder.Eventf(obj, v1.EventTypeNormal, eventReasonSkippedExcluded, "not replicating to namespace %s, namespace has an excluded label", namespace.Name)
+				replicationSummaryFrom(ctx).exclude()
+				continue
+			}
+
+			if len(allowedNamespaces) > 0 || allowedRegex != nil {
+				if utils.ListContains(literalNamespaces, namespace.Name) {
+					o.postponeNewNamespace(ctx, obj, &namespace)
+					targetNamespaces = append(targetNamespaces, namespace.Name)
+					continue
+				} else if !utils.MatchesAnyGlob(namespacePatterns, namespace.Name) && (allowedRegex == nil || !allowedRegex.MatchString(namespace.Name)) {
+					continue
+				}
+			}
+
+			if o.isSourceNamespace(obj, namespace.Name) {
+				logger.Info(fmt.Sprintf("%s in the %s namespace is a source", obj.GetName(), obj.GetNamespace()))
+				continue
+			} else if utils.ListContains(excludedNamespaces, namespace.Name) || (excludedRegex != nil && excludedRegex.MatchString(namespace.Name)) {
+				logger.Info(fmt.Sprintf("not replicating %s to namespace %s, namespace %s is an excluded namespace", obj.GetName(), namespace.Name, namespace.Name))
+				recorder.Eventf(obj, v1.EventTypeNormal, eventReasonSkippedExcluded, "not replicating to namespace %s, namespace is excluded", namespace.Name)
+				replicationSummaryFrom(ctx).exclude()
+				continue
+			} else {
+				o.postponeNewNamespace(ctx, obj, &namespace)
+				targetNamespaces = append(targetNamespaces, namespace.Name)
+			}
+		}
+
+		if namespaces.Continue == "" {
+			break
+		}
+		continueToken = namespaces.Continue
+	}
+
+	return targetNamespaces, nil
+}
+
+// namespaceOptedOut Check if a namespace opted out of replication with the opt-out annotation, this overrides
+// the target namespaces of every source
+func (o ReplicationOptions) namespaceOptedOut(namespace *v1.Namespace) bool {
+	optOut, err := strconv.ParseBool(namespace.Annotations[o.annotation(optOutKey)])
+	return err == nil && optOut
+}
+
+// skipIneligibleNamespaces Remove namespaces that don't exist, opted out of replication or are terminating from a
+// list of namespaces. Missing namespaces were already warned about by validateAllowedNamespacesExist, sources are
+// replicated to them once they are created.
+func (o ReplicationOptions) skipIneligibleNamespaces(ctx context.Context, c client.Reader, recorder record.EventRecorder, obj client.Object, namespaces []string) ([]string, error) {
+	logger := log.FromContext(ctx)
+
+	filtered := []string{}
+	for _, name := range namespaces {
+		var namespace v1.Namespace
+		if err := c.Get(ctx, client.ObjectKey{Name: name}, &namespace); errors.IsNotFound(err) {
+			logger.Info(fmt.Sprintf("not replicating %s to namespace %s, namespace %s does not exist", obj.GetName(), name, name))
+			continue
+		} else if err != nil {
+			return nil, err
+		} else if o.namespaceOptedOut(&namespace) {
+			logger.Info(fmt.Sprintf("not replicating %s to namespace %s, namespace %s opted out", obj.GetName(), name, name))
+			recorder.Eventf(obj, v1.EventTypeNormal, eventReasonSkippedExcluded, "not replicating to namespace %s, namespace opted out", name)
+			replicationSummaryFrom(ctx).exclude()
+			continue
+		} else if namespace.Status.Phase == v1.NamespaceTerminating {
+			logger.Info(fmt.Sprintf("not replicating %s to namespace %s, namespace %s is terminating", obj.GetName(), name, name))
+			continue
+		}
+
+		o.postponeNewNamespace(ctx, obj, &namespace)
+		filtered = append(filtered, name)
+	}
+
+	return filtered, nil
+}
+
+// postponeNewNamespace Postpone the replication to a target namespace created less than NewNamespaceDelay ago. The
+// namespace stays a target, so its replica isn't treated as orphaned, but isn't replicated to until the delay passed.
+func (o ReplicationOptions) postponeNewNamespace(ctx context.Context, obj client.Object, namespace *v1.Namespace) {
+	if o.NewNamespaceDelay <= 0 {
+		return
+	}
+
+	until := namespace.CreationTimestamp.Add(o.NewNamespaceDelay)
+	if !time.Now().Before(until) {
+		return
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("postponing replication of %s to namespace %s, namespace %s was created less than %s ago", obj.GetName(), namespace.Name, namespace.Name, o.NewNamespaceDelay))
+	replicationSummaryFrom(ctx).postpone(namespace.Name, until)
+}
+
+// foreignManagerAnnotation Get the first ForeignManagerAnnotations key an object carries, false if it has none
+func (o ReplicationOptions) foreignManagerAnnotation(obj metav1.Object) (string, bool) {
+	for _, key := range o.ForeignManagerAnnotations {
+		if _, ok := obj.GetAnnotations()[key]; ok {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// isSourceNamespace Check if a namespace is the namespace of the source in the local cluster, sources are never
+// replicated into their own namespace, not even under a different target-name
+func (o ReplicationOptions) isSourceNamespace(obj metav1.Object, ns string) bool {
+	return o.getTargetCluster(obj) == "" && obj.GetNamespace() == ns
+}
+
+// namespaceReader Get the reader target namespaces are listed with, apiReader when namespaces are listed in pages
+func (o ReplicationOptions) namespaceReader(c client.Client, apiReader client.Reader) client.Reader {
+	if o.NamespacePageSize > 0 && apiReader != nil {
+		return apiReader
+	}
+
+	return c
+}
+
+// forEachNamespace Run fn for every target namespace, at most ReplicationConcurrency at a time.
+// Returns the namespaces that succeeded and the errors of the namespaces that failed. Once ctx is
+// cancelled, e.g. on shutdown, the remaining namespaces fail with the context error instead of being
+// started, the namespaces in flight are waited for.
+func (o ReplicationOptions) forEachNamespace(ctx context.Context, namespaces []string, fn func(namespace string) error) ([]string, []error) {
+	results := make([]error, len(namespaces))
+	if o.ReplicationConcurrency <= 1 {
+		for i, namespace := range namespaces {
+			if err := ctx.Err(); err != nil {
+				results[i] = err
+				continue
+			}
+			results[i] = fn(namespace)
+		}
+	} else {
+		workers := make(chan struct{}, o.ReplicationConcurrency)
+		var wg sync.WaitGroup
+		for i, namespace := range namespaces {
+			if err := ctx.Err(); err != nil {
+				results[i] = err
+				continue
+			}
+
+			wg.Add(1)
+			workers <- struct{}{}
+			go func(i int, namespace string) {
+				defer wg.Done()
+				defer func() { <-workers }()
+				results[i] = fn(namespace)
+			}(i, namespace)
+		}
+		wg.Wait()
+	}
+
+	succeeded := []string{}
+	errs := []error{}
+	for i, err := range results {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", namespaces[i], err))
+		} else {
+			succeeded = append(succeeded, namespaces[i])
+		}
+	}
+	return succeeded, errs
+}
+
+// skipProtectedNamespaces Remove protected namespaces from a list of namespaces
+func (o ReplicationOptions) skipProtectedNamespaces(ctx context.Context, recorder record.EventRecorder, obj client.Object, namespaces []string) []string {
+	logger := log.FromContext(ctx)
+
+	filtered := []string{}
+	for _, namespace := range namespaces {
+		if utils.ListContains(o.ProtectedNamespaces, namespace) {
+			logger.Info(fmt.Sprintf("not replicating %s to namespace %s, namespace %s is a protected namespace", obj.GetName(), namespace, namespace))
+			recorder.Eventf(obj, v1.EventTypeNormal, eventReasonSkippedExcluded, "not replicating to namespace %s, namespace is protected", namespace)
+			replicationSummaryFrom(ctx).exclude()
+			continue
+		}
+		filtered = append(filtered, namespace)
+	}
+
+	return filtered
+}
+
+// skipGloballyDisallowedNamespaces Remove namespaces outside of GlobalAllowedNamespaces from a list of namespaces
+func (o ReplicationOptions) skipGloballyDisallowedNamespaces(ctx context.Context, recorder record.EventRecorder, obj client.Object, namespaces []string) []string {
+	logger := log.FromContext(ctx)
+
+	if len(o.GlobalAllowedNamespaces) == 0 {
+		return namespaces
+	}
+
+	filtered := []string{}
+	for _, namespace := range namespaces {
+		if !utils.ListContains(o.GlobalAllowedNamespaces, namespace) && !utils.MatchesAnyGlob(o.GlobalAllowedNamespaces, namespace) {
+			logger.Info(fmt.Sprintf("not replicating %s to namespace %s, namespace %s is not globally allowed", obj.GetName(), namespace, namespace))
+			recorder.Eventf(obj, v1.EventTypeWarning, eventReasonSkippedNotAllowed, "not replicating to namespace %s, namespace is not in the global allowed namespaces", namespace)
+			replicationSummaryFrom(ctx).exclude()
+			continue
+		}
+		filtered = append(filtered, namespace)
+	}
+
+	return filtered
+}
+
+// tooManyTargets Check if a source exceeds the target namespace limit, it isn't replicated until its scope is
+// narrowed or the limit raised
+func (o ReplicationOptions) tooManyTargets(ctx context.Context, recorder record.EventRecorder, obj client.Object, namespaces []string) bool {
+	if o.MaxTargetNamespaces <= 0 || len(namespaces) <= o.MaxTargetNamespaces {
+		return false
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("not replicating %s, %d target namespaces exceed the limit of %d", obj.GetName(), len(namespaces), o.MaxTargetNamespaces))
+	recorder.Eventf(obj, v1.EventTypeWarning, eventReasonTooManyTargets, "not replicating, %d target namespaces exceed the limit of %d", len(namespaces), o.MaxTargetNamespaces)
+	return true
+}
+
+// replicaLabels Build the labels of a replica from its source object, the replica-labels of the source and the
+// managed-by label
+func (o ReplicationOptions) replicaLabels(source metav1.Object) map[string]string {
+	labels := map[string]string{}
+	for key, value := range source.GetLabels() {
+		labels[key] = value
+	}
+
+	extraLabels, _ := o.getReplicaLabels(source)
+	for key, value := range extraLabels {
+		labels[key] = value
+	}
+
+	labels[managedByLabel] = managedByValue
+	return labels
+}
+
+// replicaMetadataChanged Check if a replica is missing any of the labels or annotations built from its source, so managed
+// metadata removed or edited on the replica is restored
+func (o ReplicationOptions) replicaMetadataChanged(replica, source metav1.Object) bool {
+	return !containsAll(replica.GetLabels(), o.replicaLabels(source)) ||
+		!containsAll(replica.GetAnnotations(), o.replicaAnnotations(source))
+}
+
+// containsAll Check if a map contains every key of another map with the same value
+func containsAll(m, subset map[string]string) bool {
+	for key, value := range subset {
+		if current, ok := m[key]; !ok || current != value {
+			return false
+		}
+	}
+	return true
+}
+
+// replicaAnnotations Build the annotations of a replica from its source object, stripping replicator control annotations.
+// If the source sets copy-annotations-prefix, only annotations with one of the prefixes are copied.
+func (o ReplicationOptions) replicaAnnotations(source metav1.Object) map[string]string {
+	stripped := []string{lastAppliedConfigAnnotation}
+	for _, key := range controlAnnotations {
+		stripped = append(stripped, o.annotation(key))
+	}
+	prefixes := o.getCopyAnnotationsPrefixes(source)
+
+	annotations := map[string]string{}
+	for key, value := range source.GetAnnotations() {
+		if utils.ListContains(stripped, key) {
+			continue
+		} else if len(prefixes) > 0 && !hasAnyPrefix(key, prefixes) {
+			continue
+		}
+		annotations[key] = value
+	}
+
+	annotations[o.annotation(replicatedFromKey)] = replicaSource(source)
+	return annotations
+}
+
+// getCopyAnnotationsPrefixes Prefixes of the source annotations copied to replicas, an empty list copies all of them
+func (o ReplicationOptions) getCopyAnnotationsPrefixes(obj metav1.Object) []string {
+	copyPrefixes, ok := obj.GetAnnotations()[o.annotation(copyAnnotationsPrefixKey)]
+	if !ok || copyPrefixes == "" {
+		return []string{}
+	}
+
+	prefixes := []string{}
+	for _, prefix := range strings.Split(copyPrefixes, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// hasAnyPrefix Check if a string starts with any of the prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeReplicaMetadata Copy the source labels and annotations onto an existing replica
+func (o ReplicationOptions) mergeReplicaMetadata(replica, source metav1.Object) {
+	labels := replica.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for key, value := range o.replicaLabels(source) {
+		labels[key] = value
+	}
+	replica.SetLabels(labels)
+
+	annotations := replica.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for key, value := range o.replicaAnnotations(source) {
+		annotations[key] = value
+	}
+	replica.SetAnnotations(annotations)
+}
+
+// replicaSource Build the replicated-from annotation value for a source object
+func replicaSource(obj metav1.Object) string {
+	return fmt.Sprintf("%s_%s", obj.GetNamespace(), obj.GetName())
+}
+
+// getReplicatedFrom Read the replicated-from annotation of a replica
+func (o ReplicationOptions) getReplicatedFrom(obj metav1.Object) (string, bool) {
+	replicatedFrom, ok := obj.GetAnnotations()[o.annotation(replicatedFromKey)]
+	return replicatedFrom, ok
+}
+
+// DefaultReconcileIntervalAnnotation Get the reconcile-interval annotation a source with replication enabled gets
+// the interval it is requeued after in, false in watch-only sync mode, if replication is disabled or the source
+// already sets an interval
+func (o ReplicationOptions) DefaultReconcileIntervalAnnotation(obj metav1.Object) (string, string, bool) {
+	if o.SyncMode == SyncModeWatchOnly || !o.replicateEnabled(obj) {
+		return "", "", false
+	}
+
+	if _, ok := obj.GetAnnotations()[o.annotation(reconciliationIntervalKey)]; ok {
+		return "", "", false
+	}
+
+	return o.annotation(reconciliationIntervalKey), o.defaultReconciliationInterval().String(), true
+}
+
+// IsReplica Check if an object was created by the replicator
+func (o ReplicationOptions) IsReplica(obj metav1.Object) bool {
+	_, ok := o.getReplicatedFrom(obj)
+	return ok
+}
+
+// isReplicaOf Check if an object carries a replicated-from annotation pointing at the source object
+func (o ReplicationOptions) isReplicaOf(obj, source metav1.Object) bool {
+	replicatedFrom, ok := o.getReplicatedFrom(obj)
+	return ok && replicatedFrom == replicaSource(source)
+}
+
+// namespaceChangedPredicate Only pass namespace create events and updates that change the namespace labels or
+// annotations, so sources with a namespace-selector or match-namespace-annotation follow namespaces that start or
+// stop matching it. A namespace that is deleted and recreated passes as a create event, or as an update with a
+// new UID, so its replicas are recreated right away instead of on the next reconcile interval.
+var namespaceChangedPredicate = predicate.Funcs{
+	CreateFunc: func(event.CreateEvent) bool { return true },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return e.ObjectOld.GetUID() != e.ObjectNew.GetUID() ||
+			!reflect.DeepEqual(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels()) ||
+			!reflect.DeepEqual(e.ObjectOld.GetAnnotations(), e.ObjectNew.GetAnnotations())
+	},
+	DeleteFunc:  func(event.DeleteEvent) bool { return false },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}
+
+// replicaPredicate Only pass events for objects carrying a replicated-from annotation
+func (o ReplicationOptions) replicaPredicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		_, ok := o.getReplicatedFrom(obj)
+		return ok
+	})
+}
+
+// sourceChangedPredicate Drop source updates that only touch server managed metadata or the annotations written by the
+// controller itself, the content function returns the fields that are copied to the replicas
+func (o ReplicationOptions) sourceChangedPredicate(content func(client.Object) interface{}) predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if !reflect.DeepEqual(e.ObjectOld.GetDeletionTimestamp(), e.ObjectNew.GetDeletionTimestamp()) ||
+				!reflect.DeepEqual(e.ObjectOld.GetFinalizers(), e.ObjectNew.GetFinalizers()) ||
+				!reflect.DeepEqual(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels()) {
+				return true
+			}
+
+			return !reflect.DeepEqual(o.sourceAnnotations(e.ObjectOld), o.sourceAnnotations(e.ObjectNew)) ||
+				!reflect.DeepEqual(content(e.ObjectOld), content(e.ObjectNew))
+		},
+	}
+}
+
+// sourceAnnotations Annotations of a source without the ones written by the controller
+func (o ReplicationOptions) sourceAnnotations(obj metav1.Object) map[string]string {
+	written := []string{o.annotation(statusKey), o.annotation(replicasKey), o.annotation(rolloutStatusKey), o.annotation(observedKey)}
+
+	annotations := map[string]string{}
+	for key, value := range obj.GetAnnotations() {
+		if utils.ListContains(written, key) {
+			continue
+		}
+		annotations[key] = value
+	}
+	return annotations
+}
+
+// parseReplicaSource Parse a replicated-from annotation value into the source object key
+func parseReplicaSource(replicatedFrom string) (types.NamespacedName, bool) {
+	namespace, name, ok := strings.Cut(replicatedFrom, "_")
+	if !ok || namespace == "" || name == "" {
+		return types.NamespacedName{}, false
+	}
+
+	return types.NamespacedName{Namespace: namespace, Name: name}, true
+}
+
+// requestsFor Build reconcile requests for a list of source objects
+func requestsFor(sources []types.NamespacedName) []reconcile.Request {
+	requests := make([]reconcile.Request, 0, len(sources))
+	for _, source := range sources {
+		requests = append(requests, reconcile.Request{NamespacedName: source})
+	}
+	return requests
+}
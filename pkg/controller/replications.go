@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ReplicationsPath Path of the endpoint listing the managed source secrets and their target namespaces
+var ReplicationsPath = "/replications"
+
+// FailuresPath Path of the endpoint listing the sources whose last reconcile failed
+var FailuresPath = "/failures"
+
+// ResyncPath Path of the endpoint re-reconciling every managed source secret
+var ResyncPath = "/resync"
+
+// replication Managed source and the namespaces it was last replicated to
+type replication struct {
+	Source  string   `json:"source"`
+	Targets []string `json:"targets"`
+}
+
+// NewReplicationsHandler Serve the entries of a SourceList and their target namespaces as JSON
+func NewReplicationsHandler(sources *SourceList) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		replications := []replication{}
+		for _, source := range sources.Items() {
+			replications = append(replications, replication{
+				Source:  source.String(),
+				Targets: sources.Targets(source),
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(replications); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// NewFailuresHandler Serve the entries of a FailureList as JSON
+func NewFailuresHandler(failures *FailureList) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(failures.Items()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// NewResyncHandler Trigger a resync of every managed source on POST requests
+func NewResyncHandler(trigger *ResyncTrigger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		trigger.Trigger()
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
@@ -0,0 +1,29 @@
+package controller
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sync"
+)
+
+// ResyncTrigger Enqueues every managed source on demand, e.g. after a global configuration change
+type ResyncTrigger struct {
+	once   sync.Once
+	events chan event.GenericEvent
+}
+
+// channel Channel the resync events are sent to, watched by the reconciler
+func (t *ResyncTrigger) channel() chan event.GenericEvent {
+	t.once.Do(func() {
+		t.events = make(chan event.GenericEvent, 1)
+	})
+	return t.events
+}
+
+// Trigger Request a resync of every managed source, a resync that is still pending isn't requested twice
+func (t *ResyncTrigger) Trigger() {
+	select {
+	case t.channel() <- event.GenericEvent{Object: &v1.Secret{}}:
+	default:
+	}
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// RolloutCanary Rollout strategy replicating new source data to the canary namespace until it is promoted
+var RolloutCanary = "canary"
+
+var (
+	rolloutPhaseCanary   = "canary"
+	rolloutPhasePromoted = "promoted"
+)
+
+// rolloutStatus Progress of the rollout of a source revision, written to the rollout-status annotation
+type rolloutStatus struct {
+	Revision string `json:"revision"`
+	Phase    string `json:"phase"`
+}
+
+// sourceRevision Hash identifying the data of a source secret, rollout-promote must be set to it to promote a canary
+func sourceRevision(secret *v1.Secret) (string, error) {
+	return replicaDataHash(secret.Type, sourceSecretData(secret))
+}
+
+// rolloutNamespaces Get the target namespaces the current revision of a source secret is rolled out to. With the canary
+// rollout, a revision that wasn't promoted yet is only replicated to the canary namespace, the other replicas keep the
+// previous revision.
+func (r *SecretReconciler) rolloutNamespaces(ctx context.Context, secret *v1.Secret, targetNamespaces []string) ([]string, error) {
+	logger := log.FromContext(ctx)
+
+	if secret.Annotations[r.annotation(rolloutKey)] != RolloutCanary {
+		return targetNamespaces, nil
+	}
+
+	revision, err := sourceRevision(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	status := rolloutStatus{Revision: revision, Phase: rolloutPhaseCanary}
+	if secret.Annotations[r.annotation(rolloutPromoteKey)] == revision {
+		status.Phase = rolloutPhasePromoted
+	}
+
+	if !r.DryRun {
+		if err := r.updateRolloutStatus(ctx, r.Client, secret, status); err != nil {
+			return nil, err
+		}
+	}
+
+	if status.Phase == rolloutPhasePromoted {
+		return targetNamespaces, nil
+	}
+
+	canaryNamespace := secret.Annotations[r.annotation(canaryNamespaceKey)]
+	logger.Info(fmt.Sprintf("revision %s of secret %s is rolled out to the canary namespace %s, set %s to promote it", revision, secret.Name, canaryNamespace, r.annotation(rolloutPromoteKey)))
+	for _, namespace := range targetNamespaces {
+		if namespace == canaryNamespace {
+			return []string{canaryNamespace}, nil
+		}
+	}
+
+	return []string{}, nil
+}
+
+// updateRolloutStatus Write the rollout-status annotation of a source, skipped if it didn't change
+func (o ReplicationOptions) updateRolloutStatus(ctx context.Context, c client.Client, obj client.Object, status rolloutStatus) error {
+	logger := log.FromContext(ctx)
+
+	statusJSON, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	if obj.GetAnnotations()[o.annotation(rolloutStatusKey)] == string(statusJSON) {
+		return nil
+	}
+
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[o.annotation(rolloutStatusKey)] = string(statusJSON)
+	obj.SetAnnotations(annotations)
+
+	if err := c.Patch(ctx, obj, patch); err != nil {
+		logger.Error(err, fmt.Sprintf("error updating rollout status of %s", obj.GetName()))
+		return err
+	}
+
+	return nil
+}
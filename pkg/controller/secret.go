@@ -1,202 +1,1351 @@
 package controller
 
 import (
+	"bytes"
 	"com.dm0275/secret-replicator-controller/utils"
 	"context"
+	goerrors "errors"
 	"fmt"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	"reflect"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
+	"net/http"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
-	"strconv"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-var (
-	annotationKey             = "secret-replicator.fussionlabs.com"
-	replicatedFromKey         = "replicated-from"
-	replicationAllowedKey     = "replication-allowed"
-	allowedNamespacesKey      = "allowed-namespaces"
-	excludedNamespacesKey     = "excluded-namespaces"
-	reconciliationIntervalKey = "reconcile-interval"
-	defaultReconcileInterval  = time.Duration(5 * time.Minute)
-)
-
 type SecretReconciler struct {
 	client.Client
+	ReplicationOptions
 	Scheme     *runtime.Scheme
-	SecretList []types.NamespacedName
+	Recorder   record.EventRecorder
+	SecretList *SourceList
+	// DryRun Log and record the intended changes to replicas without applying them
+	DryRun bool
+	// TargetClients Clients of remote clusters replicas can be written to, keyed by the target-cluster annotation
+	TargetClients map[string]client.Client
+	// APIReader Reads from the API server without the cache, used to list namespaces in pages
+	APIReader client.Reader
+	// Failures Last reconcile error of the source secrets that are currently failing
+	Failures *FailureList
+	// Resync Re-reconciles every managed source secret when triggered
+	Resync *ResyncTrigger
+	// Now Returns the current time used to measure the replication latency, defaults to time.Now
+	Now func() time.Time
+
+	// reconciledSecrets Sources reconciled at least once since the controller started
+	reconciledSecrets SourceList
+	initialSyncDone   atomic.Bool
+	// resyncIntervals Reconcile intervals of the sources requeued periodically, the liveness check passes while there
+	// are none
+	resyncIntervals resyncIntervals
+	// electedAt and lastReconciled Unix nano timestamps of the election and the last completed reconcile, 0 if they
+	// didn't happen yet
+	electedAt      atomic.Int64
+	lastReconciled atomic.Int64
+	// elected Closed once the manager is elected leader, right away without leader election
+	elected <-chan struct{}
 }
 
 func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("secret-replicator")
+	}
+	if r.SecretList == nil {
+		r.SecretList = &SourceList{}
+	}
+	if r.APIReader == nil {
+		r.APIReader = mgr.GetAPIReader()
+	}
+	if r.Failures == nil {
+		r.Failures = &FailureList{}
+	}
+	if r.Resync == nil {
+		r.Resync = &ResyncTrigger{}
+	}
+	r.elected = mgr.Elected()
+
+	// Index secrets by name, so the existing target secrets of a source are listed with a single List
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1.Secret{}, secretNameField, func(obj client.Object) []string {
+		return []string{obj.GetName()}
+	}); err != nil {
+		return err
+	}
+
+	// Rebuild the SecretList once elected, the reconciles of the previous leader left no state behind
+	if err := mgr.Add(manager.RunnableFunc(r.rebuildSecretList)); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1.Secret{}).
+		For(&v1.Secret{}, builder.WithPredicates(r.sourceChangedPredicate(secretContent))).
+		Watches(&v1.Namespace{},
+			r.coalescingHandler(r.namespaceToSources),
+			builder.WithPredicates(namespaceChangedPredicate)).
+		Watches(&v1.ConfigMap{},
+			r.coalescingHandler(r.configMapToSources)).
+		Watches(&v1.ServiceAccount{},
+			r.coalescingHandler(r.serviceAccountToSources)).
+		Watches(&v1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.replicaToSource),
+			builder.WithPredicates(r.replicaPredicate(), predicate.Funcs{
+				CreateFunc: func(event.CreateEvent) bool { return false },
+			})).
+		WatchesRawSource(&source.Channel{Source: r.Resync.channel()},
+			handler.EnqueueRequestsFromMapFunc(r.resyncToSources)).
 		Complete(r)
 }
 
-func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+// secretTypeDenied Check if the type of a source secret is in the DeniedSecretTypes, sources of denied types are
+// handled like sources with replication disabled
+func (r *SecretReconciler) secretTypeDenied(ctx context.Context, secret *v1.Secret) bool {
+	if !utils.ListContains(r.DeniedSecretTypes, string(secret.Type)) {
+		return false
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("warning: not replicating secret %s, secrets of type %s are denied", secret.Name, secret.Type))
+	r.Recorder.Eventf(secret, v1.EventTypeWarning, eventReasonSkippedDeniedType, "not replicating, secrets of type %s are denied", secret.Type)
+	return true
+}
+
+// secretNameField Field selector of the secret name, indexed in the cache and supported by the API server
+var secretNameField = "metadata.name"
+
+// secretContent Fields of a secret that are copied to its replicas
+func secretContent(obj client.Object) interface{} {
+	secret, ok := obj.(*v1.Secret)
+	if !ok {
+		return nil
+	}
+
+	return struct {
+		Type       v1.SecretType
+		Data       map[string][]byte
+		StringData map[string]string
+	}{secret.Type, secret.Data, secret.StringData}
+}
+
+// replicaToSource Enqueue the source of a replica that was deleted or drifted from its source
+func (r *SecretReconciler) replicaToSource(ctx context.Context, obj client.Object) []reconcile.Request {
+	replicatedFrom, _ := r.getReplicatedFrom(obj)
+	source, ok := parseReplicaSource(replicatedFrom)
+	if !ok {
+		return nil
+	}
+
+	replica, ok := obj.(*v1.Secret)
+	if !ok {
+		return nil
+	}
+
+	// Deleted replicas are always re-reconciled, updated replicas only if their data no longer matches the data hash
+	var replicaSecret v1.Secret
+	err := r.Get(ctx, client.ObjectKeyFromObject(replica), &replicaSecret)
+	if err == nil {
+		if !r.replicaDrifted(&replicaSecret) {
+			return nil
+		}
+
+		log.FromContext(ctx).Info(fmt.Sprintf("secret %s in namespace %s drifted from its source %s", replica.Name, replica.Namespace, replicatedFrom))
+	} else if !errors.IsNotFound(err) {
+		return nil
+	}
+
+	return requestsFor([]types.NamespacedName{source})
+}
+
+// replicaDrifted Check if the data of a replica no longer matches its data hash annotation
+func (r *SecretReconciler) replicaDrifted(secret *v1.Secret) bool {
+	dataHash, err := replicaDataHash(secret.Type, secret.Data)
+	if err != nil {
+		return true
+	}
+
+	return secret.Annotations[r.annotation(dataHashKey)] != dataHash
+}
+
+// replicaDataHash Compute the hash of the fields copied from a source secret to its replicas
+func replicaDataHash(secretType v1.SecretType, data map[string][]byte) (string, error) {
+	return utils.HashObject(struct {
+		Type v1.SecretType
+		Data map[string][]byte
+	}{secretType, data})
+}
+
+// replicaData Build the data of a replica in a namespace from the include-keys and exclude-keys projection of its
+// source, with the transform of the source applied to the transformed keys, the renamed keys renamed and templates
+// rendered for the namespace
+func (r *SecretReconciler) replicaData(sourceSecret *v1.Secret, ns string) (map[string][]byte, error) {
+	sourceData := sourceSecretData(sourceSecret)
+	keys := make([]string, 0, len(sourceData))
+	for key := range sourceData {
+		keys = append(keys, key)
+	}
+
+	// Keep empty data nil, the API server doesn't distinguish it from an empty map. The values are copied so the
+	// replica never shares memory with the cached source secret.
+	var data map[string][]byte
+	for _, key := range r.projectKeys(sourceSecret, keys) {
+		if data == nil {
+			data = map[string][]byte{}
+		}
+		data[key] = bytes.Clone(sourceData[key])
+	}
+
+	transform, err := r.getTransform(sourceSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if transform != nil {
+		transformKeys := r.getTransformKeys(sourceSecret)
+		for key, value := range data {
+			if len(transformKeys) > 0 && !utils.ListContains(transformKeys, key) {
+				continue
+			}
+
+			transformed, err := transform(value)
+			if err != nil {
+				return nil, fmt.Errorf("unable to transform key %s: %w", key, err)
+			}
+			data[key] = transformed
+		}
+	}
+
+	data, err = r.renameReplicaKeys(sourceSecret, data)
+	if err != nil {
+		return nil, err
+	}
+	return r.renderReplicaData(sourceSecret, ns, data)
+}
+
+// renameReplicaKeys Rename the data keys listed in the rename-keys annotation of a source, a renamed key can't replace
+// a key that is kept
+func (r *SecretReconciler) renameReplicaKeys(sourceSecret *v1.Secret, data map[string][]byte) (map[string][]byte, error) {
+	renames, err := r.getRenameKeys(sourceSecret)
+	if err != nil || len(renames) == 0 {
+		return data, err
+	}
+
+	renamed := make(map[string][]byte, len(data))
+	for key, value := range data {
+		if newKey, ok := renames[key]; ok {
+			key = newKey
+		}
+		if _, ok := renamed[key]; ok {
+			return nil, fmt.Errorf("unable to rename keys, key %s is replicated twice", key)
+		}
+		renamed[key] = value
+	}
+	return renamed, nil
+}
+
+// encryptReplica Encrypt the data of a replica if its source sets encrypt-with. The data hash is replaced with the
+// hash of the ciphertext, so drift is still detected, and the plaintext hash records the source data it was built from.
+func (r *SecretReconciler) encryptReplica(sourceSecret *v1.Secret, secret *v1.Secret) error {
+	encryptor, err := r.getEncryptor(sourceSecret)
+	if err != nil || encryptor == nil {
+		return err
+	}
+
+	encrypted := make(map[string][]byte, len(secret.Data))
+	for key, value := range secret.Data {
+		ciphertext, err := encryptor.Encrypt(value)
+		if err != nil {
+			return fmt.Errorf("unable to encrypt key %s: %w", key, err)
+		}
+		encrypted[key] = ciphertext
+	}
+
+	plaintextHash, err := replicaPlaintextHash(secret.Annotations[r.annotation(dataHashKey)], sourceSecret.Annotations[r.annotation(encryptWithKey)])
+	if err != nil {
+		return err
+	}
+
+	dataHash, err := replicaDataHash(secret.Type, encrypted)
+	if err != nil {
+		return err
+	}
+
+	secret.Data = encrypted
+	secret.Annotations[r.annotation(dataHashKey)] = dataHash
+	secret.Annotations[r.annotation(plaintextHashKey)] = plaintextHash
+	return nil
+}
+
+// replicaPlaintextHash Compute the hash of the unencrypted replica data and the key it is encrypted with
+func replicaPlaintextHash(dataHash string, encryptWith string) (string, error) {
+	return utils.HashObject(struct {
+		DataHash    string
+		EncryptWith string
+	}{dataHash, encryptWith})
+}
+
+// placeholderData Empty data of a placeholder replica, with the keys the API server requires for the secret type
+func placeholderData(secretType v1.SecretType) map[string][]byte {
+	switch secretType {
+	case v1.SecretTypeTLS:
+		return map[string][]byte{v1.TLSCertKey: {}, v1.TLSPrivateKeyKey: {}}
+	case v1.SecretTypeSSHAuth:
+		return map[string][]byte{v1.SSHAuthPrivateKey: {}}
+	case v1.SecretTypeBasicAuth:
+		return map[string][]byte{v1.BasicAuthUsernameKey: {}}
+	case v1.SecretTypeDockerConfigJson:
+		return map[string][]byte{v1.DockerConfigJsonKey: []byte(`{"auths":{}}`)}
+	case v1.SecretTypeDockercfg:
+		return map[string][]byte{v1.DockerConfigKey: []byte(`{}`)}
+	default:
+		return nil
+	}
+}
+
+// sourceSecretData Data of a source secret with its StringData merged in, StringData takes precedence like it
+// does when the API server normalizes the secret
+func sourceSecretData(sourceSecret *v1.Secret) map[string][]byte {
+	if len(sourceSecret.StringData) == 0 {
+		return sourceSecret.Data
+	}
+
+	data := make(map[string][]byte, len(sourceSecret.Data)+len(sourceSecret.StringData))
+	for key, value := range sourceSecret.Data {
+		data[key] = value
+	}
+	for key, value := range sourceSecret.StringData {
+		data[key] = []byte(value)
+	}
+	return data
+}
+
+// renderReplicaData Render the data of a replica as templates if the source enabled templating
+func (r *SecretReconciler) renderReplicaData(sourceSecret *v1.Secret, ns string, data map[string][]byte) (map[string][]byte, error) {
+	if !r.templateEnabled(sourceSecret) {
+		return data, nil
+	}
+
+	return renderTemplates(data, templateData{
+		Namespace:       ns,
+		Name:            r.getTargetName(sourceSecret),
+		SourceNamespace: sourceSecret.Namespace,
+		SourceName:      sourceSecret.Name,
+	})
+}
+
+// skipTemplateError Record a warning and skip a namespace the templates of a source can't be rendered for
+func (r *SecretReconciler) skipTemplateError(ctx context.Context, sourceSecret *v1.Secret, ns string, err error) bool {
 	logger := log.FromContext(ctx)
 
+	var tmplErr *templateError
+	if !goerrors.As(err, &tmplErr) {
+		return false
+	}
+
+	logger.Error(err, fmt.Sprintf("not replicating secret %s to namespace %s", sourceSecret.Name, ns))
+	r.Recorder.Eventf(sourceSecret, v1.EventTypeWarning, eventReasonTemplateFailed, "not replicating to namespace %s: %v", ns, err)
+	replicationSummaryFrom(ctx).templateSkip()
+	return true
+}
+
+// maxSecretSize Limit of the total size of the data of a secret enforced by the API server
+var maxSecretSize = 1024 * 1024
+
+// secretDataSize Total size of the keys and raw values of the data of a secret, the size the API server limits
+func secretDataSize(secret *v1.Secret) int {
+	size := 0
+	for key, value := range secret.Data {
+		size += len(key) + len(value)
+	}
+	for key, value := range secret.StringData {
+		size += len(key) + len(value)
+	}
+	return size
+}
+
+// skipTooLarge Skip a target namespace if the data of the replica exceeds the secret size limit, the API server would
+// reject it on every reconcile
+func (r *SecretReconciler) skipTooLarge(ctx context.Context, sourceSecret *v1.Secret, replica *v1.Secret) bool {
+	size := secretDataSize(replica)
+	if size <= maxSecretSize {
+		return false
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("warning: not replicating secret %s to namespace %s, the replica data is %d bytes, secrets are limited to %d bytes", sourceSecret.Name, replica.Namespace, size, maxSecretSize))
+	r.Recorder.Eventf(sourceSecret, v1.EventTypeWarning, eventReasonSecretTooLarge, "not replicating to namespace %s, the replica data is %d bytes, secrets are limited to %d bytes", replica.Namespace, size, maxSecretSize)
+	replicationSummaryFrom(ctx).tooLargeSkip()
+	return true
+}
+
+// targetClient Get the client of the cluster the replicas of a source secret are written to
+func (r *SecretReconciler) targetClient(sourceSecret *v1.Secret) (client.Client, error) {
+	cluster := r.getTargetCluster(sourceSecret)
+	if cluster == "" {
+		return r.Client, nil
+	}
+
+	targetClient, ok := r.TargetClients[cluster]
+	if !ok {
+		return nil, fmt.Errorf("unknown target cluster %s", cluster)
+	}
+
+	return targetClient, nil
+}
+
+// configMapToSources Enqueue the managed sources that read their allowed namespaces from a ConfigMap, or every source
+// when the ConfigMap is the kill switch
+func (r *SecretReconciler) configMapToSources(ctx context.Context, obj client.Object) []reconcile.Request {
+	if r.isKillSwitch(obj) {
+		return r.killSwitchToSources(ctx)
+	}
+
+	sources := []types.NamespacedName{}
+	for _, source := range r.SecretList.Items() {
+		var secret v1.Secret
+		if err := r.Get(ctx, source, &secret); err != nil {
+			continue
+		}
+
+		if reference, _, ok, err := r.getAllowedNamespacesFrom(&secret); err == nil && ok && reference == client.ObjectKeyFromObject(obj) {
+			sources = append(sources, source)
+		} else if reference, ok, err := r.getOverridesFrom(&secret); err == nil && ok && reference == client.ObjectKeyFromObject(obj) {
+			sources = append(sources, source)
+		}
+	}
+
+	return requestsFor(sources)
+}
+
+// resolveAllowedNamespacesFrom Read the allowed namespaces a source secret references with allowed-namespaces-from.
+// Returns false if the ConfigMap or key doesn't exist, the source isn't replicated until they do.
+func (r *SecretReconciler) resolveAllowedNamespacesFrom(ctx context.Context, secret *v1.Secret) ([]string, bool) {
+	logger := log.FromContext(ctx)
+
+	reference, key, ok, _ := r.getAllowedNamespacesFrom(secret)
+	if !ok {
+		return nil, true
+	}
+
+	var configMap v1.ConfigMap
+	if err := r.Get(ctx, reference, &configMap); err != nil {
+		logger.Error(err, fmt.Sprintf("not replicating secret %s, unable to read allowed namespaces from configmap %s", secret.Name, reference))
+		r.Recorder.Eventf(secret, v1.EventTypeWarning, eventReasonConfigMapNotFound, "unable to read allowed namespaces from configmap %s: %v", reference, err)
+		return nil, false
+	}
+
+	namespaces := []string{}
+	for _, namespace := range strings.Split(configMap.Data[key], ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	if len(namespaces) == 0 {
+		logger.Info(fmt.Sprintf("not replicating secret %s, configmap %s has no allowed namespaces in key %s", secret.Name, reference, key))
+		r.Recorder.Eventf(secret, v1.EventTypeWarning, eventReasonConfigMapNotFound, "configmap %s has no allowed namespaces in key %s", reference, key)
+		return nil, false
+	}
+
+	return namespaces, true
+}
+
+// resyncToSources Enqueue every managed source when a resync is triggered
+func (r *SecretReconciler) resyncToSources(ctx context.Context, obj client.Object) []reconcile.Request {
+	log.FromContext(ctx).Info(fmt.Sprintf("resyncing %d secrets", r.SecretList.Len()))
+	return requestsFor(r.SecretList.Items())
+}
+
+// resolveOverridesFrom Read the per-namespace data overrides a source secret references with overrides-from, the
+// ConfigMap keys are <namespace>.<key>. Returns false if the ConfigMap doesn't exist, the source isn't replicated until
+// it does.
+func (r *SecretReconciler) resolveOverridesFrom(ctx context.Context, secret *v1.Secret) (map[string]map[string][]byte, bool) {
+	logger := log.FromContext(ctx)
+
+	reference, ok, _ := r.getOverridesFrom(secret)
+	if !ok {
+		return nil, true
+	}
+
+	var configMap v1.ConfigMap
+	if err := r.Get(ctx, reference, &configMap); err != nil {
+		logger.Error(err, fmt.Sprintf("not replicating secret %s, unable to read overrides from configmap %s", secret.Name, reference))
+		r.Recorder.Eventf(secret, v1.EventTypeWarning, eventReasonConfigMapNotFound, "unable to read overrides from configmap %s: %v", reference, err)
+		return nil, false
+	}
+
+	overrides := map[string]map[string][]byte{}
+	addOverride := func(overrideKey string, value []byte) {
+		// Namespace names can't contain dots, the key is everything after the first one
+		namespace, key, found := strings.Cut(overrideKey, ".")
+		if !found || namespace == "" || key == "" {
+			logger.Info(fmt.Sprintf("ignoring override %s of secret %s, overrides must be named <namespace>.<key>", overrideKey, secret.Name))
+			return
+		}
+
+		if overrides[namespace] == nil {
+			overrides[namespace] = map[string][]byte{}
+		}
+		overrides[namespace][key] = value
+	}
+	for overrideKey, value := range configMap.Data {
+		addOverride(overrideKey, []byte(value))
+	}
+	for overrideKey, value := range configMap.BinaryData {
+		addOverride(overrideKey, value)
+	}
+
+	return overrides, true
+}
+
+// withOverrides Copy of a source secret with the data overrides of a target namespace applied
+func withOverrides(secret *v1.Secret, overrides map[string][]byte) *v1.Secret {
+	if len(overrides) == 0 {
+		return secret
+	}
+
+	overridden := secret.DeepCopy()
+	if overridden.Data == nil {
+		overridden.Data = map[string][]byte{}
+	}
+	for key, value := range overrides {
+		overridden.Data[key] = value
+		delete(overridden.StringData, key)
+	}
+	return overridden
+}
+
+// namespaceToSources Enqueue every managed source when a namespace is created or relabeled, target filtering happens in Reconcile
+func (r *SecretReconciler) namespaceToSources(ctx context.Context, obj client.Object) []reconcile.Request {
+	return requestsFor(r.SecretList.Items())
+}
+
+func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	logger := log.FromContext(ctx)
+	start := time.Now()
+	defer func() {
+		r.Failures.Record(req.NamespacedName, err)
+		observeReconcileDuration(start, err)
+		// Failed reconciles count as progress too, a source that keeps failing isn't fixed by a restart
+		r.lastReconciled.Store(time.Now().UnixNano())
+	}()
+
+	// Nothing is written while the kill switch is engaged, sources are enqueued again once it is released
+	if r.replicationKilled(ctx, r.Client) {
+		logger.Info(fmt.Sprintf("replication is disabled by the kill switch configmap %s, skipping secret %s", r.KillSwitch, req.NamespacedName))
+		return ctrl.Result{}, nil
+	}
+
 	var secret v1.Secret
 	if err := r.Get(ctx, req.NamespacedName, &secret); err != nil {
 		// Check if the secret is deleted
 		if errors.IsNotFound(err) {
+			r.untrackSecret(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, err
 	}
+	defer r.reconciledSecrets.Add(req.NamespacedName)
 
-	// Validate configmap configuration
-	err := r.validateConfiguration(&secret)
+	finalizer := r.annotation(finalizerKey)
+
+	// Clean up replicas if the source secret is being deleted
+	if !secret.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(&secret, finalizer) {
+			r.untrackSecret(req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, r.finalizeSecret(ctx, &secret, finalizer)
+	}
+
+	// Invalid secrets aren't managed until their configuration is fixed
+	targetClient, err := r.targetClient(&secret)
 	if err != nil {
 		logger.Error(err, "invalid secret annotation configuration")
+		r.untrackSecret(req.NamespacedName)
 		return ctrl.Result{}, err
 	}
 
-	if r.replicateEnabled(&secret) {
+	err = r.validateConfiguration(ctx, targetClient, r.Recorder, &secret)
+	if err != nil {
+		logger.Error(err, "invalid secret annotation configuration")
+		r.untrackSecret(req.NamespacedName)
+		return ctrl.Result{}, err
+	}
+
+	if r.replicateEnabled(&secret) && !r.secretTypeDenied(ctx, &secret) {
 		// If replication is enabled, add the secret to the SecretList
-		r.SecretList = utils.AppendListItem(r.SecretList, req.NamespacedName)
+		r.SecretList.Add(req.NamespacedName)
+		managedSecrets.Set(float64(r.SecretList.Len()))
+		r.checkCertificateExpiry(ctx, &secret)
+		r.trackResync(ctx, &secret)
 	} else {
+		r.untrackSecret(req.NamespacedName)
+		if replicatedFrom, ok := r.getReplicatedFrom(&secret); ok && secret.Annotations[r.annotation(replicationAllowedKey)] != "" {
+			logger.Info(fmt.Sprintf("warning: secret %s is a replica of %s, ignoring its %s annotation", secret.Name, replicatedFrom, r.annotation(replicationAllowedKey)))
+		}
+
+		// Release the source secret if replication was disabled after the finalizer was added
+		if controllerutil.RemoveFinalizer(&secret, finalizer) {
+			if err := r.Client.Update(ctx, &secret); err != nil {
+				logger.Error(err, fmt.Sprintf("error removing finalizer from secret %s", secret.Name))
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Add the finalizer so replicas can be cleaned up when the source secret is deleted
+	if !r.DryRun && controllerutil.AddFinalizer(&secret, finalizer) {
+		if err := r.Client.Update(ctx, &secret); err != nil {
+			logger.Error(err, fmt.Sprintf("error adding finalizer to secret %s", secret.Name))
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Persist when the current revision of the source was first observed, the replication latency is measured from it
+	if !r.DryRun {
+		if err := r.observeSource(ctx, r.Client, &secret); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Leave existing replicas untouched while replication is paused
+	if r.replicationPaused(&secret) {
+		logger.Info(fmt.Sprintf("replication of secret %s is paused", secret.Name))
+		return ctrl.Result{}, nil
+	}
+
+	// One-shot sources keep their replicas in place, but aren't replicated again
+	if r.oneShotCompleted(&secret) {
+		logger.Info(fmt.Sprintf("one-shot replication of secret %s already completed", secret.Name))
 		return ctrl.Result{}, nil
 	}
 
 	reconciliationInterval := r.getReconciliationInterval(ctx, &secret)
-	allowedNamespaces := r.getAllowedNamespaces(&secret)
-	if len(allowedNamespaces) > 0 {
-		for _, namespace := range allowedNamespaces {
-			r.createSecret(ctx, secret, namespace)
+
+	// An empty source is usually only half populated, don't fan it out unless explicitly allowed. Placeholders get the
+	// keys their secret type requires, and the source data once it has some.
+	if len(secret.Data) == 0 && len(secret.StringData) == 0 && r.replicatePlaceholder(&secret) {
+		secret.Data = placeholderData(secret.Type)
+	} else if len(secret.Data) == 0 && len(secret.StringData) == 0 && !r.allowEmpty(&secret) {
+		logger.Info(fmt.Sprintf("warning: secret %s has no data, skipping replication", secret.Name))
+		r.Recorder.Eventf(&secret, v1.EventTypeWarning, eventReasonSkippedEmpty, "secret has no data, set %s to replicate it", r.annotation(allowEmptyKey))
+		return ctrl.Result{RequeueAfter: reconciliationInterval}, nil
+	}
+
+	// Sources with a merge-target are merged into the secret shared with the other sources instead of replicated
+	if mergeTarget, ok, _ := r.getMergeTarget(&secret); ok {
+		replicationErrs := []error{}
+		if allowed, err := r.mergeTargetAllowed(ctx, &secret, mergeTarget); err != nil {
+			logger.Error(err, fmt.Sprintf("error checking the namespace of merge target %s", mergeTarget))
+			replicationErrs = append(replicationErrs, err)
+		} else if !allowed {
+			logger.Info(fmt.Sprintf("not merging secret %s into %s, namespace %s isn't an allowed target", secret.Name, mergeTarget, mergeTarget.Namespace))
+		} else if err := r.reconcileMergeTarget(ctx, &secret, mergeTarget); err != nil {
+			replicationErrs = append(replicationErrs, err)
 		}
-	} else {
-		var namespaces v1.NamespaceList
-		err = r.Client.List(ctx, &namespaces)
-		if err != nil {
-			logger.Error(err, "error listing namespaces")
-			return ctrl.Result{RequeueAfter: reconciliationInterval}, err
+
+		if err := r.deleteOrphanedReplicas(ctx, targetClient, &secret, []string{}); err != nil {
+			logger.Error(err, "error cleaning up orphaned replicas")
+			replicationErrs = append(replicationErrs, err)
 		}
 
-		excludedNamespaces := r.getExcludedNamespaces(&secret)
-		for _, namespace := range namespaces.Items {
-			if secret.Namespace == namespace.Name {
-				logger.Info(fmt.Sprintf("secret %s in the %s namespace is a source secret", secret.Name, secret.Namespace))
-				continue
-			} else if utils.ListContains(excludedNamespaces, namespace.Name) {
-				logger.Info(fmt.Sprintf("not replicating secret %s to namespace %s, namespace %s is an excluded namespace", secret.Name, namespace.Name, namespace.Name))
-				continue
-			} else {
-				r.createSecret(ctx, secret, namespace.Name)
-			}
+		if len(replicationErrs) > 0 {
+			return ctrl.Result{}, utilerrors.NewAggregate(replicationErrs)
 		}
+		return ctrl.Result{RequeueAfter: reconciliationInterval}, nil
 	}
 
-	return ctrl.Result{RequeueAfter: reconciliationInterval}, nil
-}
+	// Count the namespaces skipped along the way for the summary of this reconcile
+	ctx, summary := withReplicationSummary(ctx)
 
-func (r *SecretReconciler) replicateEnabled(secret *v1.Secret) bool {
-	replicationAllowed, ok := secret.Annotations[fmt.Sprintf("%s/%s", annotationKey, replicationAllowedKey)]
+	// Remote clients don't read from a cache, so only the local cluster needs the API reader for paginated lists
+	namespaceReader := client.Reader(targetClient)
+	if r.getTargetCluster(&secret) == "" {
+		namespaceReader = r.namespaceReader(r.Client, r.APIReader)
+	}
+
+	referencedNamespaces, ok := r.resolveAllowedNamespacesFrom(ctx, &secret)
 	if !ok {
-		return false
+		return ctrl.Result{RequeueAfter: reconciliationInterval}, nil
 	}
 
-	replicationAllowedBool, err := strconv.ParseBool(replicationAllowed)
+	overrides, ok := r.resolveOverridesFrom(ctx, &secret)
+	if !ok {
+		return ctrl.Result{RequeueAfter: reconciliationInterval}, nil
+	}
+
+	targetNamespaces, err := r.getTargetNamespaces(ctx, namespaceReader, r.Recorder, &secret, referencedNamespaces)
 	if err != nil {
-		return false
+		logger.Error(err, "error listing namespaces")
+		errorsTotal.WithLabelValues(operationList).Inc()
+		return ctrl.Result{RequeueAfter: reconciliationInterval}, err
+	}
+	targetNamespaces = r.skipGloballyDisallowedNamespaces(ctx, r.Recorder, &secret, targetNamespaces)
+	// Remote clients don't read from a cache, the local cluster reads pods and service accounts from the API server
+	referenceReader := client.Reader(targetClient)
+	if r.getTargetCluster(&secret) == "" {
+		referenceReader = r.APIReader
+	}
+	targetNamespaces, err = r.skipUnreferencedNamespaces(ctx, referenceReader, &secret, targetNamespaces)
+	if err != nil {
+		logger.Error(err, "error listing the service accounts and pods referencing the secret")
+		errorsTotal.WithLabelValues(operationList).Inc()
+		return ctrl.Result{RequeueAfter: reconciliationInterval}, err
+	}
+	if r.tooManyTargets(ctx, r.Recorder, &secret, targetNamespaces) {
+		return ctrl.Result{RequeueAfter: reconciliationInterval}, nil
+	}
+
+	r.SecretList.SetTargets(req.NamespacedName, targetNamespaces)
+
+	// Replicas outside of the rollout keep their data, they are still targets and not orphaned
+	rolloutNamespaces, err := r.rolloutNamespaces(ctx, &secret, targetNamespaces)
+	if err != nil {
+		logger.Error(err, "error computing the rollout namespaces")
+		return ctrl.Result{}, err
+	}
+
+	rolloutNamespaces = summary.withoutPostponed(rolloutNamespaces)
+
+	existingSecrets, err := r.listTargetSecrets(ctx, targetClient, &secret)
+	if err != nil {
+		logger.Error(err, "error listing existing target secrets")
+		errorsTotal.WithLabelValues(operationList).Inc()
+		return ctrl.Result{}, err
+	}
+
+	var written sync.Map
+	replicatedNamespaces, replicationErrs := r.forEachNamespace(ctx, rolloutNamespaces, func(namespace string) error {
+		ok, err := r.createSecret(ctx, targetClient, *withOverrides(&secret, overrides[namespace]), namespace, existingSecrets)
+		r.Failures.RecordNamespace(req.NamespacedName, namespace, err)
+		if ok {
+			written.Store(namespace, true)
+		}
+		return err
+	})
+	// Skipped namespaces succeed without a replica, only the written ones are placed
+	writtenNamespaces := []string{}
+	for _, namespace := range replicatedNamespaces {
+		if _, ok := written.Load(namespace); ok {
+			writtenNamespaces = append(writtenNamespaces, namespace)
+		}
+	}
+	summary.log(ctx, secret.Name, len(writtenNamespaces), len(replicationErrs))
+
+	// Remove replicas from namespaces that are no longer in scope
+	err = r.deleteOrphanedReplicas(ctx, targetClient, &secret, targetNamespaces)
+	if err != nil {
+		logger.Error(err, "error cleaning up orphaned replicas")
+		replicationErrs = append(replicationErrs, err)
 	}
 
-	return replicationAllowedBool
+	if !r.DryRun {
+		if err := r.updateStatus(ctx, r.Client, &secret, rolloutNamespaces, writtenNamespaces, len(rolloutNamespaces)-len(replicatedNamespaces)+summary.skippedFailures()); err != nil {
+			replicationErrs = append(replicationErrs, err)
+		}
+		observeReplicaPlacement(req.NamespacedName, r.replicaPlacement(&secret, targetNamespaces, rolloutNamespaces, writtenNamespaces, existingSecrets))
+	}
+
+	// Return replication failures so the secret is retried with backoff instead of waiting for the interval
+	if len(replicationErrs) > 0 {
+		return ctrl.Result{}, utilerrors.NewAggregate(replicationErrs)
+	}
+
+	// Retry once the first postponed namespace is old enough, sooner than the interval or in the watch-only sync mode
+	if postponedFor, ok := summary.postponedFor(r.now()); ok {
+		if reconciliationInterval == 0 || postponedFor < reconciliationInterval {
+			reconciliationInterval = postponedFor
+		}
+		return ctrl.Result{RequeueAfter: reconciliationInterval}, nil
+	}
+
+	if r.oneShot(&secret) && !r.DryRun {
+		if err := r.markOneShotCompleted(ctx, r.Client, &secret); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: reconciliationInterval}, nil
 }
 
-func (r *SecretReconciler) getAllowedNamespaces(secret *v1.Secret) []string {
-	allowedNamespaces, ok := secret.Annotations[fmt.Sprintf("%s/%s", annotationKey, allowedNamespacesKey)]
-	if !ok {
-		return []string{}
+// replicaPlacement Get the namespaces a source has a replica in, the namespaces its replica was written to and the
+// targets outside of the rollout that kept their existing replica
+func (r *SecretReconciler) replicaPlacement(secret *v1.Secret, targetNamespaces, rolloutNamespaces, writtenNamespaces []string, existing map[string]v1.Secret) []string {
+	placement := append([]string{}, writtenNamespaces...)
+	for _, namespace := range targetNamespaces {
+		if utils.ListContains(rolloutNamespaces, namespace) {
+			continue
+		}
+		if replica, ok := existing[namespace]; ok {
+			if replicatedFrom, ok := r.getReplicatedFrom(&replica); ok && replicatedFrom == replicaSource(secret) {
+				placement = append(placement, namespace)
+			}
+		}
 	}
+	return placement
+}
 
-	return strings.Split(allowedNamespaces, ",")
+// rebuildSecretList Add every enabled and valid source secret in the cluster to the SecretList
+func (r *SecretReconciler) rebuildSecretList(ctx context.Context) error {
+	logger := log.FromContext(ctx)
+	r.electedAt.Store(time.Now().UnixNano())
+
+	var secrets v1.SecretList
+	if err := r.Client.List(ctx, &secrets); err != nil {
+		logger.Error(err, "error listing secrets to rebuild the secret list")
+		errorsTotal.WithLabelValues(operationList).Inc()
+		return err
+	}
+
+	for _, secret := range secrets.Items {
+		if secret.DeletionTimestamp.IsZero() && r.replicateEnabled(&secret) && !utils.ListContains(r.DeniedSecretTypes, string(secret.Type)) && r.validateAnnotations(&secret) == nil {
+			r.SecretList.Add(client.ObjectKeyFromObject(&secret))
+			r.trackResync(ctx, &secret)
+		}
+	}
+	managedSecrets.Set(float64(r.SecretList.Len()))
+
+	logger.Info(fmt.Sprintf("rebuilt the secret list with %d secrets", r.SecretList.Len()))
+	return nil
 }
 
-func (r *SecretReconciler) getExcludedNamespaces(secret *v1.Secret) []string {
-	excludedNamespaces, ok := secret.Annotations[fmt.Sprintf("%s/%s", annotationKey, excludedNamespacesKey)]
-	if !ok {
-		return []string{}
+// InitialSyncCheck Readiness check that fails until every source secret was reconciled at least once. Standby
+// replicas don't reconcile and always pass, so a rolling update can replace the leader.
+func (r *SecretReconciler) InitialSyncCheck(req *http.Request) error {
+	if r.initialSyncDone.Load() {
+		return nil
 	}
 
-	return strings.Split(excludedNamespaces, ",")
+	select {
+	case <-r.elected:
+	default:
+		return nil
+	}
+
+	var secrets v1.SecretList
+	if err := r.Client.List(req.Context(), &secrets); err != nil {
+		return err
+	}
+
+	for _, secret := range secrets.Items {
+		source := client.ObjectKeyFromObject(&secret)
+		if r.replicateEnabled(&secret) && !r.reconciledSecrets.Contains(source) {
+			return fmt.Errorf("secret %s has not been reconciled yet", source)
+		}
+	}
+
+	r.initialSyncDone.Store(true)
+	return nil
 }
 
-func (r *SecretReconciler) validateConfiguration(secret *v1.Secret) error {
-	allowedNamespaces := r.getAllowedNamespaces(secret)
-	excludedNamespaces := r.getExcludedNamespaces(secret)
+// livenessIntervals Number of the largest reconcile intervals without a completed reconcile before the liveness
+// check fails
+var livenessIntervals = 3
+
+// LivenessCheck Health check that fails if no reconcile of a source secret completed for livenessIntervals times the
+// largest reconcile interval of the sources. Standby replicas and controllers without a source that is requeued
+// periodically, e.g. in the watch-only sync mode or when every source sets a reconcile interval of 0, always pass.
+func (r *SecretReconciler) LivenessCheck(req *http.Request) error {
+	interval := r.resyncIntervals.max()
+	if interval == 0 {
+		return nil
+	}
 
-	if utils.SlicesOverlap(allowedNamespaces, excludedNamespaces) {
-		return fmt.Errorf("unable to replicate secret %s, cannot have overlaps between allowedNamespaces and excludedNamespaces", secret.Name)
+	since := r.lastReconciled.Load()
+	if since == 0 {
+		since = r.electedAt.Load()
+	}
+	if since == 0 {
+		return nil
 	}
 
+	threshold := time.Duration(livenessIntervals) * interval
+	if elapsed := time.Since(time.Unix(0, since)); elapsed > threshold {
+		return fmt.Errorf("no secret reconcile completed for %s", elapsed.Round(time.Second))
+	}
 	return nil
 }
 
-func (r *SecretReconciler) getReconciliationInterval(ctx context.Context, secret *v1.Secret) time.Duration {
+// finalizeSecret Delete all replicas of a source secret and remove its finalizer
+func (r *SecretReconciler) finalizeSecret(ctx context.Context, secret *v1.Secret, finalizer string) error {
 	logger := log.FromContext(ctx)
-	reconciliationInterval, ok := secret.Annotations[fmt.Sprintf("%s/%s", annotationKey, reconciliationIntervalKey)]
+
+	targetClient, err := r.targetClient(secret)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("error cleaning up replicas of secret %s", secret.Name))
+		return err
+	}
+
+	replicas, err := r.listReplicas(ctx, targetClient, secret)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("error listing replicas of secret %s", secret.Name))
+		errorsTotal.WithLabelValues(operationList).Inc()
+		return err
+	}
+
+	cleanupOnDelete := r.cleanupOnDelete(secret)
+	for _, replica := range replicas {
+		// Replicas carry no owner reference, so a kept replica outlives its source as is
+		if !cleanupOnDelete {
+			continue
+		}
+
+		if r.DryRun {
+			r.logDryRun(ctx, &replica, secret, "delete secret %s in namespace %s", replica.Name, replica.Namespace)
+			continue
+		}
+
+		deleteErr := targetClient.Delete(ctx, &replica)
+		if deleteErr != nil && !errors.IsNotFound(deleteErr) {
+			errorsTotal.WithLabelValues(operationDelete).Inc()
+			logger.Error(deleteErr, fmt.Sprintf("error deleting secret %s in namespace %s", replica.Name, replica.Namespace))
+			return deleteErr
+		}
+
+		logger.Info(fmt.Sprintf("deleted secret %s in namespace %s", replica.Name, replica.Namespace))
+	}
+
+	// Keep the finalizer in dry-run mode, the replicas weren't deleted and would be orphaned without it
+	if r.DryRun {
+		r.logDryRun(ctx, secret, secret, "remove finalizer %s from secret %s", finalizer, secret.Name)
+		return nil
+	}
+
+	// Merge the remaining sources without the deleted one
+	if mergeTarget, ok, _ := r.getMergeTarget(secret); ok {
+		if err := r.reconcileMergeTarget(ctx, secret, mergeTarget); err != nil {
+			return err
+		}
+	}
+
+	controllerutil.RemoveFinalizer(secret, finalizer)
+	if err := r.Client.Update(ctx, secret); err != nil {
+		logger.Error(err, fmt.Sprintf("error removing finalizer from secret %s", secret.Name))
+		return err
+	}
+
+	r.untrackSecret(client.ObjectKeyFromObject(secret))
+	return nil
+}
+
+// trackResync Track whether a managed source is requeued periodically, paused and completed one-shot sources aren't
+func (r *SecretReconciler) trackResync(ctx context.Context, secret *v1.Secret) {
+	source := client.ObjectKeyFromObject(secret)
+	interval := r.baseReconciliationInterval(ctx, secret)
+	if interval > 0 && !r.replicationPaused(secret) && !r.oneShotCompleted(secret) {
+		r.resyncIntervals.set(source, interval)
+		return
+	}
+	r.resyncIntervals.remove(source)
+}
+
+// untrackSecret Remove a secret from the SecretList of managed sources
+func (r *SecretReconciler) untrackSecret(source types.NamespacedName) {
+	r.SecretList.Remove(source)
+	r.resyncIntervals.remove(source)
+	tlsExpiry.DeleteLabelValues(source.Namespace, source.Name)
+	forgetReplicaPlacement(source)
+	managedSecrets.Set(float64(r.SecretList.Len()))
+}
+
+// now Get the current time from the Now function of the reconciler
+func (r *SecretReconciler) now() time.Time {
+	if r.Now == nil {
+		return time.Now()
+	}
+	return r.Now()
+}
+
+// recordReplicationLatency Record the time from the current revision of a source secret being observed to now
+func (r *SecretReconciler) recordReplicationLatency(sourceSecret *v1.Secret) {
+	observedAt, ok := r.observedAt(sourceSecret)
 	if !ok {
-		return defaultReconcileInterval
+		return
+	}
+	replicationLatency.WithLabelValues(sourceSecret.Namespace).Observe(r.now().Sub(observedAt).Seconds())
+}
+
+// deleteOrphanedReplicas Delete replicas of the source secret that live outside the target namespaces
+func (r *SecretReconciler) deleteOrphanedReplicas(ctx context.Context, c client.Client, sourceSecret *v1.Secret, targetNamespaces []string) error {
+	logger := log.FromContext(ctx)
+
+	replicas, err := r.listReplicas(ctx, c, sourceSecret)
+	if err != nil {
+		errorsTotal.WithLabelValues(operationList).Inc()
+		return err
 	}
 
-	interval, err := time.ParseDuration(reconciliationInterval)
+	deleteErrs := []error{}
+	targetName := r.getTargetName(sourceSecret)
+	for _, replica := range replicas {
+		if utils.ListContains(targetNamespaces, replica.Namespace) && replica.Name == targetName {
+			continue
+		}
+
+		if r.DryRun {
+			r.logDryRun(ctx, &replica, sourceSecret, "delete orphaned secret %s in namespace %s", replica.Name, replica.Namespace)
+			continue
+		}
+
+		deleteErr := c.Delete(ctx, &replica)
+		if deleteErr != nil && !errors.IsNotFound(deleteErr) {
+			errorsTotal.WithLabelValues(operationDelete).Inc()
+			logger.Error(deleteErr, fmt.Sprintf("error deleting orphaned secret %s in namespace %s", replica.Name, replica.Namespace))
+			deleteErrs = append(deleteErrs, fmt.Errorf("namespace %s: %w", replica.Namespace, deleteErr))
+			continue
+		}
+
+		logger.Info(fmt.Sprintf("deleted orphaned secret %s in namespace %s", replica.Name, replica.Namespace))
+	}
+
+	return utilerrors.NewAggregate(deleteErrs)
+}
+
+// listReplicas List all secrets carrying a replicated-from annotation that points at the source secret
+func (r *SecretReconciler) listReplicas(ctx context.Context, c client.Client, sourceSecret *v1.Secret) ([]v1.Secret, error) {
+	var secrets v1.SecretList
+	err := c.List(ctx, &secrets)
 	if err != nil {
-		logger.Error(err, "invalid reconciliation interval")
-		return defaultReconcileInterval
+		return nil, err
 	}
 
-	return interval
+	replicas := []v1.Secret{}
+	for _, secret := range secrets.Items {
+		if r.isReplicaOf(&secret, sourceSecret) {
+			replicas = append(replicas, secret)
+		}
+	}
+
+	return replicas, nil
 }
 
-func (r *SecretReconciler) createSecret(ctx context.Context, sourceSecret v1.Secret, ns string) {
+// listTargetSecrets List the existing secrets named like the replicas of a source secret with a single List instead
+// of a Get per target namespace, keyed by namespace
+func (r *SecretReconciler) listTargetSecrets(ctx context.Context, c client.Reader, sourceSecret *v1.Secret) (map[string]v1.Secret, error) {
+	var secrets v1.SecretList
+	if err := c.List(ctx, &secrets, client.MatchingFields{secretNameField: r.getTargetName(sourceSecret)}); err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]v1.Secret, len(secrets.Items))
+	for _, secret := range secrets.Items {
+		existing[secret.Namespace] = secret
+	}
+	return existing, nil
+}
+
+// secretWriter Operations createSecret performs on replicas, lets the replica writes be replaced independently of
+// the rest of the client. New replicas are created, so a secret the cache didn't show yet is never taken over.
+// Existing replicas are updated with server-side apply, Update only replaces replicas with keys the apply can't
+// prune. The existing target secrets are listed up front by listTargetSecrets.
+type secretWriter interface {
+	Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error
+	Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error
+	Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error
+	Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error
+}
+
+// createSecret Create or update the replica of a source secret in a target namespace. Returns true once the replica
+// is in place, or would be in dry-run mode, and false if the namespace was skipped, e.g. because of a conflict.
+func (r *SecretReconciler) createSecret(ctx context.Context, c secretWriter, sourceSecret v1.Secret, ns string, existing map[string]v1.Secret) (bool, error) {
 	logger := log.FromContext(ctx)
 
-	var secret v1.Secret
-	getErr := r.Client.Get(ctx, client.ObjectKey{Name: sourceSecret.Name, Namespace: ns}, &secret)
-	if getErr != nil && errors.IsNotFound(getErr) {
-		newSecret := &v1.Secret{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      sourceSecret.Name,
-				Namespace: ns,
-				Annotations: map[string]string{
-					fmt.Sprintf("%s/%s", annotationKey, replicatedFromKey): fmt.Sprintf("%s_%s", sourceSecret.Namespace, sourceSecret.Name),
-				},
-			},
-			Data: sourceSecret.Data,
-		}
-
-		createErr := r.Client.Create(ctx, newSecret)
-		if createErr != nil {
+	// Never replicate a secret into its own namespace
+	if r.isSourceNamespace(&sourceSecret, ns) {
+		logger.Info(fmt.Sprintf("secret %s in the %s namespace is a source secret", sourceSecret.Name, ns))
+		return false, nil
+	}
+
+	secret, found := existing[ns]
+	if !found {
+		newSecret, err := r.newReplicaSecret(sourceSecret, ns)
+		if r.skipTemplateError(ctx, &sourceSecret, ns, err) {
+			return false, nil
+		} else if err != nil {
+			logger.Error(err, fmt.Sprintf("error building replica of secret %s for namespace %s", sourceSecret.Name, ns))
+			return false, err
+		}
+
+		if r.skipTooLarge(ctx, &sourceSecret, newSecret) {
+			return false, nil
+		}
+
+		if r.DryRun {
+			r.logDryRun(ctx, newSecret, &sourceSecret, "replicate secret %s to namespace %s", newSecret.Name, ns)
+			return true, nil
+		}
+
+		createErr := c.Create(ctx, newSecret, client.FieldOwner(fieldManager))
+		if errors.IsAlreadyExists(createErr) {
+			// The secret was created since the cache was read, the requeued reconcile checks whether it can be
+			// overwritten once the cache caught up
+			logger.Info(fmt.Sprintf("secret %s was created in namespace %s concurrently, retrying", newSecret.Name, ns))
+			return false, createErr
+		} else if createErr != nil {
 			logger.Error(createErr, fmt.Sprintf("error replicating secret %s to namespace %s", newSecret.Name, newSecret.Namespace))
-			return
+			r.Recorder.Eventf(&sourceSecret, v1.EventTypeWarning, eventReasonReplicationFailed, "error replicating to namespace %s: %v", ns, createErr)
+			errorsTotal.WithLabelValues(operationCreate).Inc()
+			return false, createErr
+		}
+
+		logger.Info(fmt.Sprintf("replicated secret %s to namespace %s", newSecret.Name, newSecret.Namespace))
+		r.Recorder.Eventf(&sourceSecret, v1.EventTypeNormal, eventReasonReplicated, "replicated to namespace %s", ns)
+		replicatedTotal.WithLabelValues(sourceSecret.Namespace).Inc()
+		r.recordReplicationLatency(&sourceSecret)
+	} else {
+		// Don't fight over secrets managed by another tool
+		if key, ok := r.foreignManagerAnnotation(&secret); ok {
+			logger.Info(fmt.Sprintf("not replicating secret %s to namespace %s, secret %s is managed by another tool with annotation %s", sourceSecret.Name, ns, secret.Name, key))
+			r.Recorder.Eventf(&sourceSecret, v1.EventTypeWarning, eventReasonConflictSkipped, "secret %s in namespace %s is managed by another tool with annotation %s", secret.Name, ns, key)
+			replicationSummaryFrom(ctx).conflict()
+			return false, nil
+		}
+
+		// Refuse to overwrite a replica that belongs to another source
+		replicatedFrom, ok := r.getReplicatedFrom(&secret)
+		if ok && replicatedFrom != replicaSource(&sourceSecret) {
+			logger.Info(fmt.Sprintf("not replicating secret %s to namespace %s, secret %s is already replicated from %s", sourceSecret.Name, ns, secret.Name, replicatedFrom))
+			r.Recorder.Eventf(&sourceSecret, v1.EventTypeWarning, eventReasonConflictSkipped, "secret %s in namespace %s is already replicated from %s", secret.Name, ns, replicatedFrom)
+			replicationSummaryFrom(ctx).conflict()
+			return false, nil
+		}
+
+		// Refuse to overwrite a secret that wasn't created by the controller unless the source opted in
+		if !ok && !r.overwriteExisting(&sourceSecret) {
+			logger.Info(fmt.Sprintf("not replicating secret %s to namespace %s, secret %s already exists and isn't a replica", sourceSecret.Name, ns, secret.Name))
+			r.Recorder.Eventf(&sourceSecret, v1.EventTypeWarning, eventReasonConflictSkipped, "secret %s in namespace %s already exists and isn't a replica", secret.Name, ns)
+			replicationSummaryFrom(ctx).conflict()
+			return false, nil
+		}
+
+		// The secret type can't be mutated in place, recreate the replica if it changed
+		if secret.Type != sourceSecret.Type {
+			return r.recreateSecret(ctx, c, sourceSecret, &secret)
+		}
+
+		// Check if the secret is up to date, the data hash annotation must match the source and the replica data
+		data, err := r.replicaData(&sourceSecret, ns)
+		if r.skipTemplateError(ctx, &sourceSecret, ns, err) {
+			return false, nil
+		} else if err != nil {
+			logger.Error(err, fmt.Sprintf("error building replica of secret %s for namespace %s", sourceSecret.Name, ns))
+			return false, err
 		}
-	} else if getErr == nil {
-		// Check if the secret is up to date
-		if reflect.DeepEqual(sourceSecret.Data, secret.Data) {
+
+		dataHash, err := replicaDataHash(sourceSecret.Type, data)
+		if err != nil {
+			return false, err
+		}
+
+		// Encrypted replicas differ on every write, their data hash covers the ciphertext and the plaintext hash the source
+		upToDate := secret.Annotations[r.annotation(dataHashKey)] == dataHash
+		if encryptWith := sourceSecret.Annotations[r.annotation(encryptWithKey)]; encryptWith != "" {
+			plaintextHash, err := replicaPlaintextHash(dataHash, encryptWith)
+			if err != nil {
+				return false, err
+			}
+			upToDate = secret.Annotations[r.annotation(plaintextHashKey)] == plaintextHash
+		}
+
+		if upToDate && !r.replicaDrifted(&secret) && !r.replicaMetadataChanged(&secret, &sourceSecret) {
 			logger.Info(fmt.Sprintf("secret %s is already up-to-date in namespace %s", secret.Name, ns))
-			return
+			return true, nil
+		}
+
+		// The data of immutable secrets can't be updated, recreate the replica instead
+		if secret.Immutable != nil && *secret.Immutable {
+			return r.recreateSecret(ctx, c, sourceSecret, &secret)
 		}
 
-		secret.Data = sourceSecret.Data
+		// Apply the whole replica, keys removed from the source or the projection are no longer applied and removed
+		// from the replica, fields set by other managers are kept
+		replica, err := r.newReplicaSecret(sourceSecret, ns)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("error building replica of secret %s for namespace %s", sourceSecret.Name, ns))
+			return false, err
+		}
 
-		updateErr := r.Client.Update(ctx, &secret)
+		if r.skipTooLarge(ctx, &sourceSecret, replica) {
+			return false, nil
+		}
+
+		if r.DryRun {
+			r.logDryRun(ctx, replica, &sourceSecret, "update secret %s in namespace %s", secret.Name, ns)
+			return true, nil
+		}
+
+		// Keys owned by other field managers aren't pruned by the apply, e.g. keys written before replicas were applied
+		// or added by hand. Replace the data with an Update whenever the replica has keys the source no longer has.
+		var updateErr error
+		if staleKeys := replicaStaleKeys(&secret, replica); len(staleKeys) > 0 {
+			logger.Info(fmt.Sprintf("replacing secret %s in namespace %s to remove keys %s", secret.Name, ns, strings.Join(staleKeys, ",")))
+			updateErr = r.replaceSecret(ctx, c, &secret, replica)
+		} else {
+			updateErr = r.applySecret(ctx, c, replica)
+		}
 		if updateErr != nil {
 			logger.Error(updateErr, fmt.Sprintf("error updating secret %s in namespace %s", secret.Name, secret.Namespace))
-			return
+			r.Recorder.Eventf(&sourceSecret, v1.EventTypeWarning, eventReasonReplicationFailed, "error updating replica in namespace %s: %v", ns, updateErr)
+			errorsTotal.WithLabelValues(operationUpdate).Inc()
+			return false, updateErr
 		}
 
 		logger.Info(fmt.Sprintf("updated secret %s in namespace %s", secret.Name, secret.Namespace))
-		return
-	} else {
-		logger.Error(getErr, fmt.Sprintf("error checking if secret %s exists in namespace %s", secret.Name, secret.Namespace))
+		r.Recorder.Eventf(&sourceSecret, v1.EventTypeNormal, eventReasonUpdated, "updated replica in namespace %s", ns)
+		replicatedTotal.WithLabelValues(sourceSecret.Namespace).Inc()
+		r.recordReplicationLatency(&sourceSecret)
+		return true, nil
 	}
-	return
+	return true, nil
+}
+
+// recreateSecret Delete a replica and create it again from the source secret
+func (r *SecretReconciler) recreateSecret(ctx context.Context, c secretWriter, sourceSecret v1.Secret, secret *v1.Secret) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	// Build the new replica first, the existing one is kept if it can't be replaced
+	newSecret, err := r.newReplicaSecret(sourceSecret, secret.Namespace)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("error building replica of secret %s for namespace %s", sourceSecret.Name, secret.Namespace))
+		return false, err
+	}
+
+	if r.skipTooLarge(ctx, &sourceSecret, newSecret) {
+		return false, nil
+	}
+
+	if r.DryRun {
+		r.logDryRun(ctx, secret, &sourceSecret, "recreate secret %s in namespace %s", secret.Name, secret.Namespace)
+		return true, nil
+	}
+
+	deleteErr := c.Delete(ctx, secret)
+	if deleteErr != nil && !errors.IsNotFound(deleteErr) {
+		logger.Error(deleteErr, fmt.Sprintf("error deleting secret %s in namespace %s", secret.Name, secret.Namespace))
+		r.Recorder.Eventf(&sourceSecret, v1.EventTypeWarning, eventReasonReplicationFailed, "error recreating replica in namespace %s: %v", secret.Namespace, deleteErr)
+		errorsTotal.WithLabelValues(operationDelete).Inc()
+		return false, deleteErr
+	}
+
+	createErr := c.Create(ctx, newSecret, client.FieldOwner(fieldManager))
+	if errors.IsAlreadyExists(createErr) {
+		logger.Info(fmt.Sprintf("secret %s was created in namespace %s concurrently, retrying", newSecret.Name, newSecret.Namespace))
+		return false, createErr
+	} else if createErr != nil {
+		logger.Error(createErr, fmt.Sprintf("error recreating secret %s in namespace %s", newSecret.Name, newSecret.Namespace))
+		r.Recorder.Eventf(&sourceSecret, v1.EventTypeWarning, eventReasonReplicationFailed, "error recreating replica in namespace %s: %v", newSecret.Namespace, createErr)
+		errorsTotal.WithLabelValues(operationCreate).Inc()
+		return false, createErr
+	}
+
+	logger.Info(fmt.Sprintf("recreated secret %s in namespace %s", newSecret.Name, newSecret.Namespace))
+	r.Recorder.Eventf(&sourceSecret, v1.EventTypeNormal, eventReasonUpdated, "recreated replica in namespace %s", newSecret.Namespace)
+	replicatedTotal.WithLabelValues(sourceSecret.Namespace).Inc()
+	r.recordReplicationLatency(&sourceSecret)
+	return true, nil
+}
+
+// replicaStaleKeys Get the data keys of an existing replica that the replica built from its source doesn't have, sorted
+func replicaStaleKeys(existing *v1.Secret, replica *v1.Secret) []string {
+	staleKeys := []string{}
+	for key := range existing.Data {
+		if _, ok := replica.Data[key]; !ok {
+			staleKeys = append(staleKeys, key)
+		}
+	}
+	sort.Strings(staleKeys)
+	return staleKeys
+}
+
+// replaceSecret Replace the data and managed metadata of an existing replica with an Update, the whole data map is
+// written regardless of the field managers owning its keys
+func (r *SecretReconciler) replaceSecret(ctx context.Context, c secretWriter, existing *v1.Secret, replica *v1.Secret) error {
+	updated := existing.DeepCopy()
+	updated.Data = replica.Data
+	updated.StringData = nil
+
+	if updated.Labels == nil {
+		updated.Labels = map[string]string{}
+	}
+	for key, value := range replica.Labels {
+		updated.Labels[key] = value
+	}
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	for key, value := range replica.Annotations {
+		updated.Annotations[key] = value
+	}
+
+	return c.Update(ctx, updated, client.FieldOwner(fieldManager))
+}
+
+// applySecret Update an existing replica with server-side apply, concurrent reconciles of the same replica don't
+// conflict. Only used once the replica passed the ownership checks of createSecret, it forces ownership of the fields.
+func (r *SecretReconciler) applySecret(ctx context.Context, c secretWriter, secret *v1.Secret) error {
+	secret.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"}
+	secret.ResourceVersion = ""
+	return c.Patch(ctx, secret, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership)
+}
+
+// newReplicaSecret Build the replica of a source secret for a target namespace
+func (r *SecretReconciler) newReplicaSecret(sourceSecret v1.Secret, ns string) (*v1.Secret, error) {
+	data, err := r.replicaData(&sourceSecret, ns)
+	if err != nil {
+		return nil, err
+	}
+
+	dataHash, err := replicaDataHash(sourceSecret.Type, data)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        r.getTargetName(&sourceSecret),
+			Namespace:   ns,
+			Labels:      r.replicaLabels(&sourceSecret),
+			Annotations: r.replicaAnnotations(&sourceSecret),
+		},
+		Type: sourceSecret.Type,
+		Data: data,
+	}
+	secret.Annotations[r.annotation(dataHashKey)] = dataHash
+	if r.immutableReplicas(&sourceSecret) {
+		immutable := true
+		secret.Immutable = &immutable
+	}
+
+	if err := r.encryptReplica(&sourceSecret, secret); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// logDryRun Log and record an intended change to a replica while in dry-run mode
+func (r *SecretReconciler) logDryRun(ctx context.Context, replica *v1.Secret, sourceSecret *v1.Secret, format string, args ...interface{}) {
+	logger := log.FromContext(ctx)
+
+	message := fmt.Sprintf(format, args...)
+	logger.Info(fmt.Sprintf("dry-run: would %s", message), "replica", client.ObjectKeyFromObject(replica))
+	r.Recorder.Eventf(sourceSecret, v1.EventTypeNormal, eventReasonDryRun, "dry-run: would %s", message)
 }
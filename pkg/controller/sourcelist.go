@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"com.dm0275/secret-replicator-controller/utils"
+	"k8s.io/apimachinery/pkg/types"
+	"sync"
+)
+
+// SourceList Concurrency-safe list of the source objects managed by a reconciler
+type SourceList struct {
+	mu      sync.RWMutex
+	items   []types.NamespacedName
+	targets map[types.NamespacedName][]string
+}
+
+func (l *SourceList) Add(item types.NamespacedName) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.items = utils.AppendListItem(l.items, item)
+}
+
+func (l *SourceList) Remove(item types.NamespacedName) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.items = utils.RemoveListItem(l.items, item)
+	delete(l.targets, item)
+}
+
+// SetTargets Record the namespaces a source was last replicated to
+func (l *SourceList) SetTargets(item types.NamespacedName, targets []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.targets == nil {
+		l.targets = map[types.NamespacedName][]string{}
+	}
+	l.targets[item] = append([]string{}, targets...)
+}
+
+// Targets Return a copy of the namespaces a source was last replicated to
+func (l *SourceList) Targets(item types.NamespacedName) []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]string{}, l.targets[item]...)
+}
+
+// Items Return a copy of the list items
+func (l *SourceList) Items() []types.NamespacedName {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]types.NamespacedName{}, l.items...)
+}
+
+func (l *SourceList) Contains(item types.NamespacedName) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, listItem := range l.items {
+		if listItem == item {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *SourceList) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.items)
+}
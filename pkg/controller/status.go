@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"strings"
+)
+
+// replicationStatus Result of the last replication of a source, stored as JSON in the status annotation
+type replicationStatus struct {
+	Targets        []string    `json:"targets"`
+	Succeeded      int         `json:"succeeded"`
+	Failed         int         `json:"failed"`
+	LastReplicated metav1.Time `json:"lastReplicated"`
+}
+
+// updateStatus Write the status and replicas annotations on a source object, written lists the target namespaces
+// the source has a replica in and failed counts the target namespaces that failed, like the summary log. The
+// annotations are only written when the targets or results changed, so the update event it causes doesn't
+// retrigger replication indefinitely.
+func (o ReplicationOptions) updateStatus(ctx context.Context, c client.Client, obj client.Object, targets []string, written []string, failed int) error {
+	logger := log.FromContext(ctx)
+
+	status := replicationStatus{
+		Targets:        targets,
+		Succeeded:      len(written),
+		Failed:         failed,
+		LastReplicated: metav1.Now(),
+	}
+	replicas := strings.Join(written, ",")
+
+	var currentStatus replicationStatus
+	if current, ok := obj.GetAnnotations()[o.annotation(statusKey)]; ok && json.Unmarshal([]byte(current), &currentStatus) == nil {
+		if reflect.DeepEqual(currentStatus.Targets, status.Targets) && currentStatus.Succeeded == status.Succeeded && currentStatus.Failed == status.Failed &&
+			obj.GetAnnotations()[o.annotation(replicasKey)] == replicas {
+			return nil
+		}
+	}
+
+	statusJSON, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[o.annotation(statusKey)] = string(statusJSON)
+	annotations[o.annotation(replicasKey)] = replicas
+	obj.SetAnnotations(annotations)
+
+	if err := c.Patch(ctx, obj, patch); err != nil {
+		logger.Error(err, fmt.Sprintf("error updating status of %s", obj.GetName()))
+		return err
+	}
+
+	return nil
+}
+
+// markOneShotCompleted Record on a one-shot source that it was fully replicated
+func (o ReplicationOptions) markOneShotCompleted(ctx context.Context, c client.Client, obj client.Object) error {
+	logger := log.FromContext(ctx)
+
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[o.annotation(oneShotCompletedKey)] = "true"
+	obj.SetAnnotations(annotations)
+
+	if err := c.Patch(ctx, obj, patch); err != nil {
+		logger.Error(err, fmt.Sprintf("error marking one-shot replication of %s as completed", obj.GetName()))
+		return err
+	}
+
+	return nil
+}
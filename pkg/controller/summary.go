@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// replicationSummary Counts of the namespaces a reconcile skipped, logged as a single summary line
+type replicationSummary struct {
+	excluded        atomic.Int64
+	conflicts       atomic.Int64
+	templateSkipped atomic.Int64
+	tooLarge        atomic.Int64
+
+	mu sync.Mutex
+	// postponed Target namespaces created less than NewNamespaceDelay ago, with the time they become eligible
+	postponed map[string]time.Time
+}
+
+type replicationSummaryKey struct{}
+
+// withReplicationSummary Attach a new replicationSummary to a reconcile context
+func withReplicationSummary(ctx context.Context) (context.Context, *replicationSummary) {
+	summary := &replicationSummary{}
+	return context.WithValue(ctx, replicationSummaryKey{}, summary), summary
+}
+
+// replicationSummaryFrom Get the replicationSummary of a reconcile context, nil if it has none
+func replicationSummaryFrom(ctx context.Context) *replicationSummary {
+	summary, _ := ctx.Value(replicationSummaryKey{}).(*replicationSummary)
+	return summary
+}
+
+// exclude Count a target namespace that was skipped because it is excluded, protected, not allowed or didn't opt in
+func (s *replicationSummary) exclude() {
+	if s != nil {
+		s.excluded.Add(1)
+	}
+}
+
+// conflict Count a target namespace that was skipped because of a conflicting secret
+func (s *replicationSummary) conflict() {
+	if s != nil {
+		s.conflicts.Add(1)
+	}
+}
+
+// templateSkip Count a target namespace that was skipped because its template couldn't be rendered
+func (s *replicationSummary) templateSkip() {
+	if s != nil {
+		s.templateSkipped.Add(1)
+	}
+}
+
+// tooLargeSkip Count a target namespace that was skipped because its replica exceeds the secret size limit
+func (s *replicationSummary) tooLargeSkip() {
+	if s != nil {
+		s.tooLarge.Add(1)
+	}
+}
+
+// postpone Record a target namespace that isn't replicated to until the given time
+func (s *replicationSummary) postpone(namespace string, until time.Time) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.postponed == nil {
+		s.postponed = map[string]time.Time{}
+	}
+	s.postponed[namespace] = until
+}
+
+// withoutPostponed Remove the postponed target namespaces from a list of namespaces
+func (s *replicationSummary) withoutPostponed(namespaces []string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filtered := []string{}
+	for _, namespace := range namespaces {
+		if _, ok := s.postponed[namespace]; !ok {
+			filtered = append(filtered, namespace)
+		}
+	}
+	return filtered
+}
+
+// postponedFor Get the time until the first postponed target namespace becomes eligible, false if none was postponed
+func (s *replicationSummary) postponedFor(now time.Time) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.postponed) == 0 {
+		return 0, false
+	}
+
+	var first time.Time
+	for _, until := range s.postponed {
+		if first.IsZero() || until.Before(first) {
+			first = until
+		}
+	}
+	return first.Sub(now), true
+}
+
+// log Log the summary of a reconcile, written are the namespaces a replica was written to and failed the namespaces
+// createSecret returned an error for
+func (s *replicationSummary) log(ctx context.Context, name string, written int, failed int) {
+	failed += s.skippedFailures()
+	log.FromContext(ctx).Info(fmt.Sprintf("replicated %s to %d namespaces, skipped %d (excluded), skipped %d (conflict), failed %d",
+		name, written, s.excluded.Load(), s.conflicts.Load(), failed),
+		"replicated", written, "excluded", s.excluded.Load(), "conflicts", s.conflicts.Load(), "failed", failed)
+}
+
+// skippedFailures Count the skipped namespaces that are reported as failed, their replica couldn't be built
+func (s *replicationSummary) skippedFailures() int {
+	return int(s.templateSkipped.Load() + s.tooLarge.Load())
+}
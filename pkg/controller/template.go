@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// templateData Fields data value templates can reference
+type templateData struct {
+	// Namespace Target namespace of the replica
+	Namespace string
+	// Name Name of the replica
+	Name string
+	// SourceNamespace Namespace of the source
+	SourceNamespace string
+	// SourceName Name of the source
+	SourceName string
+}
+
+// templateError Data value that can't be rendered, the target namespace is skipped
+type templateError struct {
+	key string
+	err error
+}
+
+func (e *templateError) Error() string {
+	return fmt.Sprintf("unable to render the template of key %s: %v", e.key, e.err)
+}
+
+func (e *templateError) Unwrap() error {
+	return e.err
+}
+
+// renderTemplates Render each data value as a Go template
+func renderTemplates(data map[string][]byte, values templateData) (map[string][]byte, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	rendered := make(map[string][]byte, len(data))
+	for key, value := range data {
+		tmpl, err := template.New(key).Option("missingkey=error").Parse(string(value))
+		if err != nil {
+			return nil, &templateError{key: key, err: err}
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, values); err != nil {
+			return nil, &templateError{key: key, err: err}
+		}
+		rendered[key] = buf.Bytes()
+	}
+
+	return rendered, nil
+}
@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"context"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+// DefaultClientTimeout Timeout of a single client operation
+var DefaultClientTimeout = time.Duration(30 * time.Second)
+
+// DefaultGracefulShutdownTimeout Time in-flight reconciles get to finish when the controller shuts down
+var DefaultGracefulShutdownTimeout = time.Duration(30 * time.Second)
+
+// timeoutClient Client applying a timeout to every operation, so a slow API server can't stall a reconcile
+type timeoutClient struct {
+	client.Client
+	timeout time.Duration
+}
+
+// NewTimeoutClient Wrap a client so each operation is cancelled after the timeout, a timeout of 0 disables it
+func NewTimeoutClient(c client.Client, timeout time.Duration) client.Client {
+	if timeout <= 0 {
+		return c
+	}
+
+	return &timeoutClient{Client: c, timeout: timeout}
+}
+
+func (c *timeoutClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func (c *timeoutClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Client.List(ctx, list, opts...)
+}
+
+func (c *timeoutClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *timeoutClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *timeoutClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *timeoutClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func (c *timeoutClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.Client.DeleteAllOf(ctx, obj, opts...)
+}
+
+// timeoutReader Reader applying a timeout to every read, used for the reads bypassing the cache
+type timeoutReader struct {
+	client.Reader
+	timeout time.Duration
+}
+
+// NewTimeoutReader Wrap a reader so each read is cancelled after the timeout, a timeout of 0 disables it
+func NewTimeoutReader(r client.Reader, timeout time.Duration) client.Reader {
+	if timeout <= 0 {
+		return r
+	}
+
+	return &timeoutReader{Reader: r, timeout: timeout}
+}
+
+func (r *timeoutReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	return r.Reader.Get(ctx, key, obj, opts...)
+}
+
+func (r *timeoutReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	return r.Reader.List(ctx, list, opts...)
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"time"
+)
+
+// certificateExpiry Parse the expiry of the first certificate of a PEM encoded certificate chain
+func certificateExpiry(data []byte) (time.Time, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return time.Time{}, fmt.Errorf("no PEM encoded certificate found")
+	}
+
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return certificate.NotAfter, nil
+}
+
+// checkCertificateExpiry Export the certificate expiry of a TLS source secret, and warn if it expires within the
+// TLSExpiryWarning window
+func (r *SecretReconciler) checkCertificateExpiry(ctx context.Context, secret *v1.Secret) {
+	if secret.Type != v1.SecretTypeTLS {
+		return
+	}
+
+	expiry, err := certificateExpiry(secret.Data[v1.TLSCertKey])
+	if err != nil {
+		log.FromContext(ctx).Error(err, fmt.Sprintf("unable to parse the certificate of secret %s", secret.Name))
+		tlsExpiry.DeleteLabelValues(secret.Namespace, secret.Name)
+		return
+	}
+	tlsExpiry.WithLabelValues(secret.Namespace, secret.Name).Set(float64(expiry.Unix()))
+
+	if remaining := time.Until(expiry); r.TLSExpiryWarning > 0 && remaining < r.TLSExpiryWarning {
+		log.FromContext(ctx).Info(fmt.Sprintf("warning: the certificate of secret %s expires at %s", secret.Name, expiry.Format(time.RFC3339)))
+		r.Recorder.Eventf(secret, v1.EventTypeWarning, eventReasonCertificateExpiring, "certificate expires at %s", expiry.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,68 @@
+package encrypt
+
+import (
+	"bytes"
+	"filippo.io/age"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Encryptor Encrypts the values of a replica before it is written, consumers decrypt them with the matching key
+type Encryptor interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+}
+
+var (
+	Age = "age"
+)
+
+var encryptors = map[string]func(key string) (Encryptor, error){
+	Age: newAgeEncryptor,
+}
+
+// Get Build the encryptor of a <scheme>:<key> specification, e.g. age:<recipient>
+func Get(spec string) (Encryptor, error) {
+	scheme, key, found := strings.Cut(strings.TrimSpace(spec), ":")
+	if !found || key == "" {
+		return nil, fmt.Errorf("%s is not a <scheme>:<key> pair", spec)
+	}
+
+	newEncryptor, ok := encryptors[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption scheme %s", scheme)
+	}
+
+	return newEncryptor(key)
+}
+
+// ageEncryptor Encrypts values to an age X25519 recipient
+type ageEncryptor struct {
+	recipient age.Recipient
+}
+
+func newAgeEncryptor(key string) (Encryptor, error) {
+	recipient, err := age.ParseX25519Recipient(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ageEncryptor{recipient: recipient}, nil
+}
+
+func (e *ageEncryptor) Encrypt(plaintext []byte) ([]byte, error) {
+	var ciphertext bytes.Buffer
+	writer, err := age.Encrypt(&ciphertext, e.recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.Copy(writer, bytes.NewReader(plaintext)); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return ciphertext.Bytes(), nil
+}
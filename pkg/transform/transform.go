@@ -0,0 +1,45 @@
+package transform
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// Transform Function applied to the value of a replicated key before it is written to a replica
+type Transform func(value []byte) ([]byte, error)
+
+var (
+	Base64Decode = "base64-decode"
+	Base64Encode = "base64-encode"
+)
+
+var transforms = map[string]Transform{
+	Base64Decode: base64Decode,
+	Base64Encode: base64Encode,
+}
+
+// Get Look up a transform by name
+func Get(name string) (Transform, error) {
+	transform, ok := transforms[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown transform %s", name)
+	}
+
+	return transform, nil
+}
+
+func base64Decode(value []byte) ([]byte, error) {
+	decoded := make([]byte, base64.StdEncoding.DecodedLen(len(value)))
+	n, err := base64.StdEncoding.Decode(decoded, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return decoded[:n], nil
+}
+
+func base64Encode(value []byte) ([]byte, error) {
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(value)))
+	base64.StdEncoding.Encode(encoded, value)
+	return encoded, nil
+}
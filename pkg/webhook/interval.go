@@ -0,0 +1,48 @@
+package webhook
+
+import (
+	"com.dm0275/secret-replicator-controller/pkg/controller"
+	"context"
+	"encoding/json"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// IntervalDefaulterPath Path the reconcile interval mutating webhook is served on
+var IntervalDefaulterPath = "/mutate-v1-secret-reconcile-interval"
+
+// IntervalDefaulter Sets the reconcile-interval annotation of sources without one to the default interval, so the
+// effective interval is visible on the source
+type IntervalDefaulter struct {
+	controller.ReplicationOptions
+	Decoder *admission.Decoder
+}
+
+func (d *IntervalDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	var secret v1.Secret
+	if err := d.Decoder.Decode(req, &secret); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	key, interval, ok := d.DefaultReconcileIntervalAnnotation(&secret)
+	if !ok {
+		return admission.Allowed("")
+	}
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[key] = interval
+
+	mutated, err := json.Marshal(&secret)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, mutated)
+}
@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"com.dm0275/secret-replicator-controller/pkg/controller"
+	"context"
+	"fmt"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ReplicaValidatorPath Path the replica validating webhook is served on
+var ReplicaValidatorPath = "/validate-v1-secret-replica"
+
+// ReplicaValidator Rejects updates to replicated secrets unless they come from the controller
+type ReplicaValidator struct {
+	controller.ReplicationOptions
+	// ControllerUsername Username of the controller's service account, e.g. system:serviceaccount:<namespace>:<name>
+	ControllerUsername string
+	Decoder            *admission.Decoder
+}
+
+func (v *ReplicaValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	if req.UserInfo.Username == v.ControllerUsername {
+		return admission.Allowed("update from the secret replicator")
+	}
+
+	var secret v1.Secret
+	if err := v.Decoder.DecodeRaw(req.OldObject, &secret); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if !v.IsReplica(&secret) {
+		return admission.Allowed("")
+	}
+
+	return admission.Denied(fmt.Sprintf("secret %s is a replica managed by the secret replicator and is read-only", secret.Name))
+}
@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"com.dm0275/secret-replicator-controller/pkg/controller"
+	"context"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SourceValidatorPath Path the source validating webhook is served on
+var SourceValidatorPath = "/validate-v1-secret-source"
+
+// SourceValidator Rejects secrets with invalid replication annotations
+type SourceValidator struct {
+	controller.ReplicationOptions
+	Decoder *admission.Decoder
+}
+
+func (v *SourceValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	var secret v1.Secret
+	if err := v.Decoder.Decode(req, &secret); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if err := v.ValidateSource(&secret); err != nil {
+		return admission.Denied(err.Error())
+	}
+
+	return admission.Allowed("")
+}
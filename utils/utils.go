@@ -1,6 +1,15 @@
 package utils
 
-import "os"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
 
 func ListContains(s []string, e string) bool {
 	for _, a := range s {
@@ -31,6 +40,24 @@ func SlicesOverlap(slice1, slice2 []string) bool {
 	return false
 }
 
+// SlicesIntersection Return the elements of slice1 that are also in slice2, in the order of slice1
+func SlicesIntersection(slice1, slice2 []string) []string {
+	// Create a map to store elements from slice2
+	seen := make(map[string]bool)
+	for _, elem := range slice2 {
+		seen[elem] = true
+	}
+
+	intersection := []string{}
+	for _, elem := range slice1 {
+		if seen[elem] {
+			intersection = AppendListItem(intersection, elem)
+		}
+	}
+
+	return intersection
+}
+
 func GetEnv(envVar, defaultVal string) string {
 	environmentVar, exists := os.LookupEnv(envVar)
 	if !exists {
@@ -39,6 +66,73 @@ func GetEnv(envVar, defaultVal string) string {
 	return environmentVar
 }
 
+// GetEnvBool Read a boolean environment variable, returns the default value if it is unset or invalid
+func GetEnvBool(envVar string, defaultVal bool) bool {
+	environmentVar, exists := os.LookupEnv(envVar)
+	if !exists {
+		return defaultVal
+	}
+
+	value, err := strconv.ParseBool(environmentVar)
+	if err != nil {
+		return defaultVal
+	}
+	return value
+}
+
+// GetEnvDuration Read a duration environment variable, returns the default value and the parse error if it is invalid
+func GetEnvDuration(envVar string, defaultVal time.Duration) (time.Duration, error) {
+	environmentVar, exists := os.LookupEnv(envVar)
+	if !exists {
+		return defaultVal, nil
+	}
+
+	value, err := time.ParseDuration(environmentVar)
+	if err != nil {
+		return defaultVal, err
+	}
+	return value, nil
+}
+
+// GetEnvInt Read an integer environment variable, returns the default value and the parse error if it is invalid
+func GetEnvInt(envVar string, defaultVal int) (int, error) {
+	environmentVar, exists := os.LookupEnv(envVar)
+	if !exists {
+		return defaultVal, nil
+	}
+
+	value, err := strconv.Atoi(environmentVar)
+	if err != nil {
+		return defaultVal, err
+	}
+	return value, nil
+}
+
+// GetEnvFloat Read a floating point environment variable, returns the default value and the parse error if it is invalid
+func GetEnvFloat(envVar string, defaultVal float64) (float64, error) {
+	environmentVar, exists := os.LookupEnv(envVar)
+	if !exists {
+		return defaultVal, nil
+	}
+
+	value, err := strconv.ParseFloat(environmentVar, 64)
+	if err != nil {
+		return defaultVal, err
+	}
+	return value, nil
+}
+
+// HashObject Compute a SHA-256 hash of the JSON encoding of a value, map keys are sorted so the hash is stable
+func HashObject(value interface{}) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(encoded)
+	return hex.EncodeToString(hash[:]), nil
+}
+
 func AppendListItem[T comparable](list []T, item T) []T {
 	for _, listItem := range list {
 		if listItem == item {
@@ -48,3 +142,42 @@ func AppendListItem[T comparable](list []T, item T) []T {
 
 	return append(list, item)
 }
+
+func RemoveListItem[T comparable](list []T, item T) []T {
+	for i, listItem := range list {
+		if listItem == item {
+			return append(list[:i], list[i+1:]...)
+		}
+	}
+
+	return list
+}
+
+// IsGlobPattern Check if a string contains glob pattern characters
+func IsGlobPattern(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// SplitGlobPatterns Split a list into its literal entries and its glob pattern entries
+func SplitGlobPatterns(list []string) ([]string, []string) {
+	literals := []string{}
+	patterns := []string{}
+	for _, item := range list {
+		if IsGlobPattern(item) {
+			patterns = append(patterns, item)
+		} else {
+			literals = append(literals, item)
+		}
+	}
+	return literals, patterns
+}
+
+// MatchesAnyGlob Check if a string matches any of the glob patterns
+func MatchesAnyGlob(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, s); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}